@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 服务端错误分类：Firebase 令牌接口和 Warp GraphQL 返回的错误统一转换为 *apiError，
+// 调用方用 isAPIError 按类型判断，不依赖错误文本；界面显示 Error() 给出的中文说明。
+
+// apiErrorKind 错误类型
+type apiErrorKind int
+
+const (
+	apiErrUnknown        apiErrorKind = iota
+	apiErrTokenExpired                // 登录已过期或 refresh_token 已被撤销
+	apiErrTokenInvalid                // refresh_token 格式错误或不属于该项目
+	apiErrUserDisabled                // 账号已被停用
+	apiErrUserNotFound                // 账号不存在或已删除
+	apiErrInvalidAPIKey               // Firebase API key 无效
+	apiErrQuotaExceeded               // 配额用尽
+	apiErrRateLimited                 // 请求过于频繁
+	apiErrUniqueConflict              // 唯一键冲突（对象已存在）
+)
+
+var apiErrorMessages = map[apiErrorKind]string{
+	apiErrTokenExpired:   "登录已过期或 refresh_token 已被撤销，请重新获取 refresh_token 后登录",
+	apiErrTokenInvalid:   "refresh_token 无效，请检查是否完整复制",
+	apiErrUserDisabled:   "该账号已被停用",
+	apiErrUserNotFound:   "账号不存在或已被删除",
+	apiErrInvalidAPIKey:  "Firebase API key 无效，请检查设置",
+	apiErrQuotaExceeded:  "服务端配额已用尽，请稍后再试",
+	apiErrRateLimited:    "请求过于频繁，请稍后再试",
+	apiErrUniqueConflict: "对象已存在（唯一键冲突）",
+}
+
+// apiError 分类后的服务端错误
+type apiError struct {
+	Kind   apiErrorKind
+	Status int    // HTTP 状态码，未知时为 0
+	Code   string // 服务端错误码，如 TOKEN_EXPIRED、UniqueKeyConflict
+	Detail string // 服务端原始消息
+}
+
+func (e *apiError) Error() string {
+	if msg, ok := apiErrorMessages[e.Kind]; ok {
+		return msg
+	}
+	detail := e.Detail
+	if detail == "" {
+		detail = e.Code
+	}
+	switch {
+	case detail != "" && e.Status != 0:
+		return fmt.Sprintf("服务端错误（http %d）: %s", e.Status, detail)
+	case detail != "":
+		return "服务端错误: " + detail
+	case e.Status != 0:
+		return fmt.Sprintf("http %d", e.Status)
+	}
+	return "服务端错误"
+}
+
+// isAPIError 判断 err 是否为指定类型的服务端错误
+func isAPIError(err error, kind apiErrorKind) bool {
+	var e *apiError
+	return errors.As(err, &e) && e.Kind == kind
+}
+
+// needsRelogin 错误是否意味着当前登录已不可用，需要用户重新登录
+func needsRelogin(err error) bool {
+	var e *apiError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Kind {
+	case apiErrTokenExpired, apiErrTokenInvalid, apiErrUserDisabled, apiErrUserNotFound:
+		return true
+	}
+	return false
+}
+
+// firebaseErrorKinds Firebase 令牌接口的错误码
+var firebaseErrorKinds = map[string]apiErrorKind{
+	"TOKEN_EXPIRED":               apiErrTokenExpired,
+	"INVALID_REFRESH_TOKEN":       apiErrTokenInvalid,
+	"INVALID_GRANT_TYPE":          apiErrTokenInvalid,
+	"MISSING_REFRESH_TOKEN":       apiErrTokenInvalid,
+	"PROJECT_NUMBER_MISMATCH":     apiErrTokenInvalid,
+	"USER_DISABLED":               apiErrUserDisabled,
+	"USER_NOT_FOUND":              apiErrUserNotFound,
+	"API_KEY_INVALID":             apiErrInvalidAPIKey,
+	"QUOTA_EXCEEDED":              apiErrQuotaExceeded,
+	"RESOURCE_EXHAUSTED":          apiErrQuotaExceeded,
+	"TOO_MANY_ATTEMPTS_TRY_LATER": apiErrRateLimited,
+}
+
+// parseFirebaseError 解析令牌接口的错误响应：
+// {"error":{"code":400,"message":"TOKEN_EXPIRED","status":"INVALID_ARGUMENT","details":[{"reason":"API_KEY_INVALID"}]}}
+func parseFirebaseError(status int, body []byte) *apiError {
+	e := &apiError{Status: status}
+	var r struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				Reason string `json:"reason"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &r) != nil || r.Error.Message == "" {
+		e.Detail = strings.TrimSpace(string(body))
+		if status == http.StatusTooManyRequests {
+			e.Kind = apiErrRateLimited
+		}
+		return e
+	}
+	e.Detail = r.Error.Message
+	// message 可能带说明，如 "TOO_MANY_ATTEMPTS_TRY_LATER : Access to this account has been temporarily disabled"
+	code, _, _ := strings.Cut(r.Error.Message, " : ")
+	candidates := []string{strings.TrimSpace(code)}
+	for _, d := range r.Error.Details {
+		candidates = append(candidates, d.Reason)
+	}
+	candidates = append(candidates, r.Error.Status)
+	for _, c := range candidates {
+		if kind, ok := firebaseErrorKinds[c]; ok {
+			e.Kind, e.Code = kind, c
+			return e
+		}
+	}
+	e.Code = candidates[0]
+	if status == http.StatusTooManyRequests {
+		e.Kind = apiErrRateLimited
+	}
+	return e
+}
+
+// gqlUserErrorKinds UserFacingError.error.__typename 与错误类型的对应
+var gqlUserErrorKinds = map[string]apiErrorKind{
+	"UniqueKeyConflict": apiErrUniqueConflict,
+}
+
+// apiError 把 UserFacingError 转换为分类错误
+func (e *gqlUserError) apiError() *apiError {
+	return &apiError{Kind: gqlUserErrorKinds[e.Typename], Code: e.Typename, Detail: e.Message}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 自动备份：程序运行期间按设置在登录后以及每隔一段时间备份当前账号。
+// 每次先从云端获取配置，与该账号最新一份备份（含手动备份）的内容哈希比较，只有内容变化时才写入新备份。
+// 令牌过期时沿用 gqlClient 的刷新逻辑（刷新结果会写回全局缓存）。
+
+var errAutoBackupBusy = errors.New("上一次自动备份仍在进行")
+
+// autoBackupModes 界面上的自动备份选项：登录后是否立即备份、定时间隔（0 表示不定时）
+var autoBackupModes = []struct {
+	Label   string
+	OnLogin bool
+	Minutes int
+}{
+	{"关闭", false, 0},
+	{"登录后", true, 0},
+	{"每 30 分钟", true, 30},
+	{"每小时", true, 60},
+	{"每 6 小时", true, 360},
+	{"每天", true, 1440},
+}
+
+// autoBackupModeIndex 返回与设置对应的选项下标
+func autoBackupModeIndex(s appSettings) int {
+	for i, m := range autoBackupModes {
+		if m.OnLogin == s.AutoBackupOnLogin && m.Minutes == s.AutoBackupMinutes {
+			return i
+		}
+	}
+	return 0
+}
+
+// backupContentHash 备份内容的哈希，不含备份时间等元信息，用于判断云端配置是否有变化。
+// 条目按规范化内容排序后再计算，增量合并与全量获取得到的顺序不同不算变化
+func backupContentHash(bd BackupData) string {
+	// serializedModel 是 JSON 字符串，先规范化，避免键顺序不同被当作变化
+	objects := make([]map[string]any, 0, len(bd.MCPServers)+len(bd.Rules)+len(bd.Objects))
+	for _, m := range bd.genericObjects() {
+		objects = append(objects, map[string]any{
+			"format":          m["format"],
+			"serializedModel": canonicalJSON(asString(m["serializedModel"])),
+			"folderId":        m["folderId"],
+		})
+	}
+	// 云端元数据（uid、更新时间）不算内容；文件夹的 uid 用于表示层级，保留
+	content := map[string][]string{
+		"generic_objects": sortedEntries(objects),
+		"workflows":       sortedEntries(withoutKeys(bd.Workflows, metaUIDKey, metaTsKey)),
+		"notebooks":       sortedEntries(withoutKeys(bd.Notebooks, metaUIDKey, metaTsKey)),
+		"folders":         sortedEntries(withoutKeys(bd.Folders, metaTsKey)),
+	}
+	b, _ := json.Marshal(content)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortedEntries 把条目转为规范化 JSON 并排序
+func sortedEntries(entries []map[string]any) []string {
+	out := make([]string, len(entries))
+	for i, m := range entries {
+		b, _ := json.Marshal(m)
+		out[i] = canonicalJSON(string(b))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// withoutKeys 返回去掉指定键的条目副本
+func withoutKeys(entries []map[string]any, keys ...string) []map[string]any {
+	out := make([]map[string]any, len(entries))
+	for i, m := range entries {
+		c := make(map[string]any, len(m))
+		for k, v := range m {
+			c[k] = v
+		}
+		for _, k := range keys {
+			delete(c, k)
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// latestBackupHash 读取账号最新一份备份的内容哈希，没有或无法读取（如口令不对）时为空
+func latestBackupHash(email, passphrase string) string {
+	bd, err := latestAccountBackup(email, passphrase)
+	if err != nil {
+		return ""
+	}
+	return backupContentHash(bd)
+}
+
+// autoBackupResult 一次自动备份的结果
+type autoBackupResult struct {
+	Time    time.Time
+	Changed bool        // 内容有变化并已写入新备份
+	Saved   savedBackup // 新写入的备份，Changed 为 false 时为空
+	Data    BackupData
+}
+
+// autoBackupScheduler 定时触发自动备份，并保证同一时间只有一次备份在进行
+type autoBackupScheduler struct {
+	mu   sync.Mutex
+	busy bool
+	stop chan struct{}
+}
+
+// SetInterval 重新设置定时间隔并在每次到期时调用 tick；interval 为 0 时停止定时
+func (s *autoBackupScheduler) SetInterval(interval time.Duration, tick func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				tick()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Run 执行一次自动备份：内容与上次相同则不写文件
+func (s *autoBackupScheduler) Run(ctx context.Context, idToken, refreshToken, userID, email string, opts backupOptions) (autoBackupResult, error) {
+	s.mu.Lock()
+	if s.busy {
+		s.mu.Unlock()
+		return autoBackupResult{}, errAutoBackupBusy
+	}
+	s.busy = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.busy = false
+		s.mu.Unlock()
+	}()
+
+	res := autoBackupResult{Time: time.Now()}
+	bd, err := fetchBackupData(ctx, idToken, refreshToken, userID, email, opts)
+	if err != nil {
+		return res, err
+	}
+	res.Data = bd
+	if backupContentHash(bd) == latestBackupHash(email, opts.Passphrase) {
+		return res, nil
+	}
+	if res.Saved, err = saveBackupFile(bd, opts.Passphrase); err != nil {
+		return res, err
+	}
+	res.Changed = true
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBackupContentHashIgnoresOrderAndMetadata(t *testing.T) {
+	bd := BackupData{
+		MCPServers: []map[string]any{
+			mcpEntry("m1", "1", `{"name":"a","x":1}`),
+			mcpEntry("m2", "1", `{"name":"b"}`),
+			mcpEntry("m3", "1", `{"name":"c"}`),
+		},
+		Workflows: []map[string]any{
+			{"data": `{"name":"w1"}`, "folderId": "", metaUIDKey: "w1", metaTsKey: "1"},
+			{"data": `{"name":"w2"}`, "folderId": "f1", metaUIDKey: "w2", metaTsKey: "1"},
+		},
+		Folders: []map[string]any{
+			{"uid": "f1", "name": "A", "folderId": "", metaTsKey: "1"},
+			{"uid": "f2", "name": "B", "folderId": "f1", metaTsKey: "1"},
+		},
+	}
+	want := backupContentHash(bd)
+
+	shuffled := bd
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		shuffled.MCPServers = shuffledEntries(r, bd.MCPServers)
+		shuffled.Workflows = shuffledEntries(r, bd.Workflows)
+		shuffled.Folders = shuffledEntries(r, bd.Folders)
+		if got := backupContentHash(shuffled); got != want {
+			t.Fatalf("条目顺序不同时哈希变化")
+		}
+	}
+
+	// 更新时间和 serializedModel 的键顺序不算内容
+	touched := bd
+	touched.MCPServers = []map[string]any{
+		mcpEntry("m1", "9", `{"x":1,"name":"a"}`),
+		mcpEntry("m2", "9", `{"name":"b"}`),
+		mcpEntry("m3", "9", `{"name":"c"}`),
+	}
+	if backupContentHash(touched) != want {
+		t.Error("只有元数据变化时哈希不应变化")
+	}
+
+	changed := bd
+	changed.MCPServers = append(shuffledEntries(r, bd.MCPServers[:2]), mcpEntry("m3", "2", `{"name":"c2"}`))
+	if backupContentHash(changed) == want {
+		t.Error("内容变化时哈希应变化")
+	}
+}
+
+func shuffledEntries(r *rand.Rand, entries []map[string]any) []map[string]any {
+	out := append([]map[string]any(nil), entries...)
+	r.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 备份加密：口令经 PBKDF2-SHA256 派生密钥，使用 AES-256-GCM 认证加密。
+// 加密后的文件仍是 JSON，只包含算法参数和密文，不暴露任何备份内容。
+
+const (
+	backupCipherName   = "aes-256-gcm"
+	backupKDFName      = "pbkdf2-sha256"
+	backupKDFIter      = 600000
+	backupCryptoAADTag = "warpmini-backup-v1"
+)
+
+var (
+	errPassphraseRequired = errors.New("备份已加密，需要输入口令")
+	errWrongPassphrase    = errors.New("口令错误或备份文件已被篡改")
+)
+
+// encryptedBackup 加密备份文件的外层结构
+type encryptedBackup struct {
+	Encrypted  string `json:"encrypted"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// isEncryptedBackup 判断文件内容是否为加密备份
+func isEncryptedBackup(data []byte) bool {
+	var probe struct {
+		Encrypted string `json:"encrypted"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Encrypted != ""
+}
+
+// encryptBackup 用口令加密备份明文
+func encryptBackup(plain []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := backupGCM(passphrase, salt, backupKDFIter)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	env := encryptedBackup{
+		Encrypted:  backupCipherName,
+		KDF:        backupKDFName,
+		Iterations: backupKDFIter,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plain, []byte(backupCryptoAADTag))),
+	}
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// decryptBackup 用口令解密备份，口令为空时返回 errPassphraseRequired
+func decryptBackup(data []byte, passphrase string) ([]byte, error) {
+	var env encryptedBackup
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Encrypted != backupCipherName || env.KDF != backupKDFName {
+		return nil, fmt.Errorf("不支持的加密方式: %s/%s", env.Encrypted, env.KDF)
+	}
+	if passphrase == "" {
+		return nil, errPassphraseRequired
+	}
+	salt, err1 := base64.StdEncoding.DecodeString(env.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(env.Nonce)
+	ct, err3 := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err := errors.Join(err1, err2, err3); err != nil || env.Iterations <= 0 {
+		return nil, errors.New("加密备份格式错误")
+	}
+	gcm, err := backupGCM(passphrase, salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("加密备份格式错误")
+	}
+	plain, err := gcm.Open(nil, nonce, ct, []byte(backupCryptoAADTag))
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return plain, nil
+}
+
+func backupGCM(passphrase string, salt []byte, iter int) (cipher.AEAD, error) {
+	key := pbkdf2SHA256([]byte(passphrase), salt, iter, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 按 RFC 8018 派生密钥（标准库在 go1.21 尚未提供）
+func pbkdf2SHA256(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	out := make([]byte, 0, blocks*hashLen)
+	buf := make([]byte, 4)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+// useTempHome 把 HOME 指向临时目录，备份和设置都写在其中
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv(envBackupDir, "")
+	return home
+}
+
+func TestEncryptDecryptBackup(t *testing.T) {
+	plain := []byte(`{"backup_time":"t","format":"simplified"}`)
+	data, err := encryptBackup(plain, "口令 123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedBackup(data) || bytes.Contains(data, []byte("simplified")) {
+		t.Fatalf("加密结果不应包含明文: %s", data)
+	}
+	got, err := decryptBackup(data, "口令 123")
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("解密 = %q, %v", got, err)
+	}
+	if _, err := decryptBackup(data, "wrong"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("口令错误时应返回 errWrongPassphrase，实际 %v", err)
+	}
+	if _, err := decryptBackup(data, ""); !errors.Is(err, errPassphraseRequired) {
+		t.Errorf("未提供口令时应返回 errPassphraseRequired，实际 %v", err)
+	}
+}
+
+func TestSaveLoadEncryptedBackup(t *testing.T) {
+	useTempHome(t)
+	bd := newImportedBackup("mcp_json", "mcp.json", "a@example.com")
+	bd.MCPServers = append(bd.MCPServers, map[string]any{"format": formatMCPServer, "serializedModel": `{"name":"a"}`})
+	saved, err := saveBackupFile(bd, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := saved.Path
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("备份文件权限应为 0600: %v %v", info.Mode().Perm(), err)
+	}
+	got, err := loadBackupFile(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.MCPServers) != 1 || got.MCPServers[0]["serializedModel"] != `{"name":"a"}` {
+		t.Errorf("读取结果不一致: %v", got.MCPServers)
+	}
+	if _, err := loadBackupFile(path, "wrong"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("口令错误时应返回 errWrongPassphrase，实际 %v", err)
+	}
+	if _, err := loadBackupFile(path, ""); !errors.Is(err, errPassphraseRequired) {
+		t.Errorf("未提供口令时应返回 errPassphraseRequired，实际 %v", err)
+	}
+}
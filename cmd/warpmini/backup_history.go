@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 备份历史：每次备份按账号写入一个带时间戳的新文件，
+// 位于 <备份根目录>/<账号>/config_backup_<时间>.json；
+// 备份根目录默认 ~/.warp_config/backups，可通过设置或环境变量 WARPMINI_BACKUP_DIR 指定（见 settings.go）
+
+const (
+	backupFilePrefix     = "config_backup_"
+	backupFileExt        = ".json"
+	backupTimeLayout     = "20060102-150405.000"
+	legacyBackupName     = "config_backup.json"
+	backupKeepPerAccount = 20 // 每个账号保留的最新备份数量，更早的自动清理
+)
+
+// backupEntry 描述历史中的一个备份文件
+type backupEntry struct {
+	Path    string
+	Account string // 账号目录名，旧版单文件备份为空
+	Time    time.Time
+}
+
+// Label 用于界面展示
+func (e backupEntry) Label() string {
+	account := e.Account
+	if account == "" {
+		account = "旧版备份"
+	}
+	return fmt.Sprintf("%s · %s", e.Time.Format("2006-01-02 15:04:05"), account)
+}
+
+// warpConfigDir 返回 ~/.warp_config，不存在时创建（仅当前用户可访问）
+func warpConfigDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".warp_config")
+	if err := ensurePrivateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// ensurePrivateDir 创建目录并收紧权限为 0700；备份中可能含有 API 密钥
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return os.Chmod(dir, 0o700)
+}
+
+// backupsRootDir 返回存放各账号备份目录的根目录
+func backupsRootDir() (string, error) {
+	if dir, err := configuredBackupDir(); err != nil || dir != "" {
+		return dir, err
+	}
+	base, err := warpConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "backups"), nil
+}
+
+// accountDirName 把邮箱转换为安全的目录名。替换字符会让不同邮箱（a+b@x 与 a_b@x）得到相同的名字，
+// 因此追加邮箱哈希的前 8 位；旧版本写入的不带哈希的目录仍会出现在备份列表中，但不再写入
+func accountDirName(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "unknown"
+	}
+	var sb strings.Builder
+	for _, r := range email {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	sum := sha256.Sum256([]byte(email))
+	return sb.String() + "-" + hex.EncodeToString(sum[:4])
+}
+
+// newBackupPath 为账号生成一个尚未使用的带时间戳的备份路径
+func newBackupPath(email string, t time.Time) (string, error) {
+	root, err := backupsRootDir()
+	if err != nil {
+		return "", err
+	}
+	// 根目录可能是用户指定的共享或同步目录：不存在时才创建，不改动已有目录的权限；
+	// 备份文件的保密由账号目录（0700）和文件（0600）保证
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, accountDirName(email))
+	if err := ensurePrivateDir(dir); err != nil {
+		return "", err
+	}
+	stamp := t.Format(backupTimeLayout)
+	path := filepath.Join(dir, backupFilePrefix+stamp+backupFileExt)
+	// 极少数情况下时间戳重复时追加序号，避免覆盖
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s%s-%d%s", backupFilePrefix, stamp, i, backupFileExt))
+	}
+}
+
+// parseBackupTime 从文件名解析备份时间，失败时使用文件修改时间
+func parseBackupTime(path string, info os.FileInfo) time.Time {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), backupFilePrefix), backupFileExt)
+	if len(name) >= len(backupTimeLayout) {
+		if t, err := time.ParseInLocation(backupTimeLayout, name[:len(backupTimeLayout)], time.Local); err == nil {
+			return t
+		}
+	}
+	return info.ModTime()
+}
+
+// listAccountBackups 列出某个账号目录下的备份，最新的在前
+func listAccountBackups(dir, account string) ([]backupEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*"+backupFileExt))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]backupEntry, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		entries = append(entries, backupEntry{Path: m, Account: account, Time: parseBackupTime(m, info)})
+	}
+	sortBackups(entries)
+	return entries, nil
+}
+
+// listBackups 列出所有账号的备份（含旧版单文件备份），最新的在前
+func listBackups() ([]backupEntry, error) {
+	root, err := backupsRootDir()
+	if err != nil {
+		return nil, err
+	}
+	var all []backupEntry
+	dirs, err := os.ReadDir(root)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		entries, err := listAccountBackups(filepath.Join(root, d.Name()), d.Name())
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+	}
+	// 兼容旧版固定路径的备份
+	base, err := warpConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	legacy := filepath.Join(base, legacyBackupName)
+	if info, err := os.Stat(legacy); err == nil && !info.IsDir() {
+		all = append(all, backupEntry{Path: legacy, Time: info.ModTime()})
+	}
+	sortBackups(all)
+	return all, nil
+}
+
+func sortBackups(entries []backupEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Path > entries[j].Path
+		}
+		return entries[i].Time.After(entries[j].Time)
+	})
+}
+
+// pruneBackups 按保留策略清理账号目录中较旧的备份
+func pruneBackups(dir string, keep int) error {
+	entries, err := listAccountBackups(dir, filepath.Base(dir))
+	if err != nil {
+		return fmt.Errorf("清理旧备份失败: %w", err)
+	}
+	if keep <= 0 || len(entries) <= keep {
+		return nil
+	}
+	var errs []string
+	for _, e := range entries[keep:] {
+		for _, p := range []string{e.Path, checksumPath(e.Path)} {
+			if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Sprintf("%s: %v", p, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("清理旧备份失败: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
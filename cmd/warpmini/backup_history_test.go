@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveBackupPrunesOldBackups(t *testing.T) {
+	useTempHome(t)
+	paths := saveTestBackups(t, backupKeepPerAccount+2)
+	entries, err := listAccountBackups(filepath.Dir(paths[0]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != backupKeepPerAccount || entries[0].Path != paths[len(paths)-1] {
+		t.Fatalf("应保留最新的 %d 份，实际 %d 份", backupKeepPerAccount, len(entries))
+	}
+	for _, p := range paths[:2] {
+		if _, err := os.Stat(checksumPath(p)); !os.IsNotExist(err) {
+			t.Errorf("%s 的校验文件应一并删除", p)
+		}
+	}
+}
+
+func TestSaveBackupReportsPruneFailure(t *testing.T) {
+	useTempHome(t)
+	paths := saveTestBackups(t, backupKeepPerAccount)
+	// 最早备份的校验文件换成非空目录，删除必然失败
+	sum := checksumPath(paths[0])
+	if err := os.Remove(sum); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(sum, "x"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := saveBackupFile(newImportedBackup("mcp_json", "mcp.json", "a@example.com"), "")
+	if err != nil {
+		t.Fatalf("清理失败不应导致备份失败: %v", err)
+	}
+	if saved.PruneErr == nil || !strings.Contains(saved.pruneWarning(), "清理旧备份失败") {
+		t.Errorf("应报告清理失败，实际 %v", saved.PruneErr)
+	}
+	if _, err := os.Stat(saved.Path); err != nil {
+		t.Errorf("新备份应已写入: %v", err)
+	}
+}
+
+func TestAccountDirName(t *testing.T) {
+	if a, b := accountDirName("a+b@x.com"), accountDirName("a_b@x.com"); a == b {
+		t.Errorf("不同邮箱得到相同目录名 %s", a)
+	}
+	if a, b := accountDirName(" A@X.com "), accountDirName("a@x.com"); a != b {
+		t.Errorf("同一邮箱大小写不同时目录名应相同: %s / %s", a, b)
+	}
+	if name := accountDirName("a/../b@x.com"); strings.ContainsAny(name, `/\`) || !strings.HasPrefix(name, "a_.._b@x.com-") {
+		t.Errorf("目录名不安全: %s", name)
+	}
+}
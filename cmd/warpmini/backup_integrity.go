@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 备份完整性：备份先写入同目录的临时文件并 fsync，再原子重命名到目标路径，
+// 崩溃或磁盘写满时不会留下半截文件。每个备份旁边另存一个 sha256sum 格式的校验文件，
+// 读取时校验；校验失败或内容无法解析时报告“备份已损坏”，并指出上一个完好的备份。
+
+const backupChecksumExt = ".sha256"
+
+var errBackupCorrupt = errors.New("备份已损坏")
+
+// corruptBackupError 描述损坏的备份及可替代的上一个完好备份
+type corruptBackupError struct {
+	Path     string
+	Reason   string
+	Previous string // 同账号中更早的完好备份，没有时为空
+}
+
+func (e *corruptBackupError) Error() string {
+	msg := fmt.Sprintf("备份已损坏（%s）: %s", e.Reason, e.Path)
+	if e.Previous != "" {
+		msg += "；上一个完好的备份: " + e.Previous
+	} else {
+		msg += "；没有找到更早的完好备份"
+	}
+	return msg
+}
+
+func (e *corruptBackupError) Unwrap() error { return errBackupCorrupt }
+
+// checksumPath 返回备份对应的校验文件路径
+func checksumPath(path string) string {
+	return path + backupChecksumExt
+}
+
+// writeFileAtomic 先写临时文件并落盘，再重命名到 path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return fail(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir 尽量把目录项落盘（Windows 不支持，忽略错误）
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+// writeBackupChecksum 写入备份的校验文件，格式与 sha256sum 输出一致
+func writeBackupChecksum(path string, data []byte) error {
+	sum := sha256.Sum256(data)
+	line := hex.EncodeToString(sum[:]) + "  " + filepath.Base(path) + "\n"
+	return writeFileAtomic(checksumPath(path), []byte(line), 0o600)
+}
+
+// verifyBackupChecksum 校验备份内容；没有校验文件（旧备份）时视为通过
+func verifyBackupChecksum(path string, data []byte) error {
+	raw, err := os.ReadFile(checksumPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	want, _, _ := strings.Cut(strings.TrimSpace(string(raw)), " ")
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(want, hex.EncodeToString(sum[:])) {
+		return errors.New("校验和不匹配")
+	}
+	return nil
+}
+
+// backupLooksIntact 不需要口令的完整性检查：校验和通过且内容可解析
+// （加密备份只检查外层结构）
+func backupLooksIntact(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil || verifyBackupChecksum(path, data) != nil {
+		return false
+	}
+	if isEncryptedBackup(data) {
+		return true
+	}
+	_, err = decodeBackup(data)
+	return err == nil
+}
+
+// previousGoodBackup 返回同一账号目录中比 path 更早的第一个完好备份
+func previousGoodBackup(path string) string {
+	entries, err := listAccountBackups(filepath.Dir(path), "")
+	if err != nil {
+		return ""
+	}
+	older := false
+	for _, e := range entries {
+		if e.Path == path {
+			older = true
+			continue
+		}
+		if older && backupLooksIntact(e.Path) {
+			return e.Path
+		}
+	}
+	return ""
+}
+
+// newCorruptBackupError 构造损坏错误并查找可替代的备份
+func newCorruptBackupError(path, reason string) error {
+	return &corruptBackupError{Path: path, Reason: reason, Previous: previousGoodBackup(path)}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// saveTestBackups 依次保存 n 份备份，返回路径（最早的在前）
+func saveTestBackups(t *testing.T, n int) []string {
+	t.Helper()
+	var paths []string
+	for i := 0; i < n; i++ {
+		saved, err := saveBackupFile(newImportedBackup("mcp_json", "mcp.json", "a@example.com"), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, saved.Path)
+		time.Sleep(2 * time.Millisecond) // 文件名精确到毫秒
+	}
+	return paths
+}
+
+func TestLoadBackupDetectsCorruption(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, paths []string)
+		broken int // 被损坏的备份
+		want   int // 期望给出的上一个完好备份，-1 表示没有
+	}{
+		{
+			"内容被改动",
+			func(t *testing.T, p []string) { appendToFile(t, p[2], " ") },
+			2, 1,
+		},
+		{
+			"校验文件不匹配，上一份也已损坏",
+			func(t *testing.T, p []string) {
+				os.WriteFile(checksumPath(p[2]), []byte("00 x\n"), 0o600)
+				appendToFile(t, p[1], "x")
+			},
+			2, 0,
+		},
+		{
+			"最早的备份损坏",
+			func(t *testing.T, p []string) { os.WriteFile(p[0], []byte("{}\n"), 0o600) },
+			0, -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempHome(t)
+			paths := saveTestBackups(t, 3)
+			tt.damage(t, paths)
+			_, err := loadBackupFile(paths[tt.broken], "")
+			var corrupt *corruptBackupError
+			if !errors.As(err, &corrupt) || !errors.Is(err, errBackupCorrupt) {
+				t.Fatalf("应返回 corruptBackupError，实际 %v", err)
+			}
+			want := ""
+			if tt.want >= 0 {
+				want = paths[tt.want]
+			}
+			if corrupt.Previous != want {
+				t.Errorf("上一个完好备份 = %q，期望 %q", corrupt.Previous, want)
+			}
+		})
+	}
+}
+
+func TestLoadBackupWithoutChecksum(t *testing.T) {
+	useTempHome(t)
+	path := saveTestBackups(t, 1)[0]
+	// 旧版本写入的备份没有校验文件，仍可读取
+	if err := os.Remove(checksumPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBackupFile(path, ""); err != nil {
+		t.Errorf("没有校验文件时应正常读取: %v", err)
+	}
+}
+
+func appendToFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
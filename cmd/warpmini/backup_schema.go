@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 备份格式版本：每次不兼容的结构调整都把 backupSchemaVersion 加一，并在 backupMigrations
+// 中登记从上一版本升级的函数。读取时逐版本升级到当前版本，再做校验；
+// 高于当前版本的备份由更新的 warpmini 生成，直接拒绝，避免静默丢失字段。
+//
+// 版本 1：早期备份，没有 schema_version 字段（version 为 "2.3"）
+// 版本 2：增加 schema_version，genericStringObject 统一使用 format + serializedModel
+
+const backupSchemaVersion = 2
+
+var errNotBackupFile = errors.New("不是 warpmini 备份文件或文件已损坏")
+
+// backupMigrations 版本 N -> N+1 的升级函数，直接修改解析后的原始 JSON
+var backupMigrations = map[int]func(raw map[string]any) error{
+	1: migrateBackupV1,
+}
+
+// migrateBackupV1 补全分组内缺少的 format，兼容旧字段 serialized_model，空分组改为空列表
+func migrateBackupV1(raw map[string]any) error {
+	sections := map[string]string{"mcp_servers": formatMCPServer, "rules": formatAIFact, "generic_objects": "", "workflows": "", "notebooks": "", "folders": ""}
+	for key, format := range sections {
+		arr, ok := raw[key].([]any)
+		if raw[key] != nil && !ok {
+			return fmt.Errorf("%s 应为列表", key)
+		}
+		if arr == nil {
+			raw[key] = []any{}
+			continue
+		}
+		for i, it := range arr {
+			m, ok := it.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s[%d] 应为对象", key, i)
+			}
+			if format == "" {
+				continue
+			}
+			if _, ok := m["serializedModel"]; !ok && m["serialized_model"] != nil {
+				m["serializedModel"] = m["serialized_model"]
+				delete(m, "serialized_model")
+			}
+			if asString(m["format"]) == "" {
+				m["format"] = format
+			}
+		}
+	}
+	return nil
+}
+
+// decodeBackup 解析备份明文：识别版本、逐版本升级并校验
+func decodeBackup(data []byte) (BackupData, error) {
+	var b BackupData
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		return b, errNotBackupFile
+	}
+	version, err := backupVersionOf(raw)
+	if err != nil {
+		return b, err
+	}
+	if version > backupSchemaVersion {
+		return b, fmt.Errorf("备份格式版本 %d 高于当前支持的版本 %d，请升级 warpmini 后再恢复", version, backupSchemaVersion)
+	}
+	for v := version; v < backupSchemaVersion; v++ {
+		migrate, ok := backupMigrations[v]
+		if !ok {
+			return b, fmt.Errorf("不支持从备份格式版本 %d 升级", v)
+		}
+		if err := migrate(raw); err != nil {
+			return b, fmt.Errorf("升级备份格式（版本 %d）失败: %w", v, err)
+		}
+	}
+	raw["schema_version"] = backupSchemaVersion
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(migrated, &b); err != nil {
+		return b, fmt.Errorf("备份结构错误: %w", err)
+	}
+	if err := validateBackup(b); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// backupVersionOf 读取 schema_version；没有该字段的旧备份视为版本 1
+func backupVersionOf(raw map[string]any) (int, error) {
+	v, ok := raw["schema_version"]
+	if !ok {
+		// 旧备份至少带有备份时间和格式标记，其他 JSON 不当作备份
+		if asString(raw["backup_time"]) == "" || asString(raw["format"]) == "" {
+			return 0, errNotBackupFile
+		}
+		return 1, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 1 || f != float64(int(f)) {
+		return 0, fmt.Errorf("备份格式版本无效: %v", v)
+	}
+	return int(f), nil
+}
+
+// validateBackup 检查当前版本备份的必填字段
+func validateBackup(b BackupData) error {
+	var problems []error
+	if b.BackupTime == "" {
+		problems = append(problems, errors.New("缺少 backup_time"))
+	}
+	if b.Format != "simplified" {
+		problems = append(problems, fmt.Errorf("不支持的备份格式 %q", b.Format))
+	}
+	for i, m := range b.genericObjects() {
+		if asString(m["format"]) == "" {
+			problems = append(problems, fmt.Errorf("第 %d 个对象缺少 format", i+1))
+		}
+		if _, ok := m["serializedModel"].(string); !ok {
+			problems = append(problems, fmt.Errorf("第 %d 个对象缺少 serializedModel", i+1))
+		}
+	}
+	for i, f := range b.Folders {
+		if asString(f["uid"]) == "" {
+			problems = append(problems, fmt.Errorf("第 %d 个文件夹缺少 uid", i+1))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("备份文件校验失败: %w", errors.Join(problems...))
+	}
+	return nil
+}
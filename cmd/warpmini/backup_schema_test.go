@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBackupMigratesV1(t *testing.T) {
+	// 早期备份：没有 schema_version，分组内缺少 format，旧字段名 serialized_model，部分分组缺失
+	const v1 = `{
+		"backup_time": "2024-05-01T10:00:00+08:00",
+		"backup_type": "global",
+		"version": "2.3",
+		"format": "simplified",
+		"mcp_servers": [{"serialized_model": "{\"name\":\"a\"}"}],
+		"rules": [{"serializedModel": "{\"memory\":{\"name\":\"r\"}}"}]
+	}`
+	bd, err := decodeBackup([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bd.SchemaVersion != backupSchemaVersion {
+		t.Errorf("SchemaVersion = %d，期望 %d", bd.SchemaVersion, backupSchemaVersion)
+	}
+	if len(bd.MCPServers) != 1 || bd.MCPServers[0]["format"] != formatMCPServer || bd.MCPServers[0]["serializedModel"] != `{"name":"a"}` {
+		t.Errorf("MCP 配置未正确升级: %v", bd.MCPServers)
+	}
+	if _, ok := bd.MCPServers[0]["serialized_model"]; ok {
+		t.Error("旧字段 serialized_model 应已移除")
+	}
+	if len(bd.Rules) != 1 || bd.Rules[0]["format"] != formatAIFact {
+		t.Errorf("规则未补全 format: %v", bd.Rules)
+	}
+	if bd.Workflows == nil || bd.Notebooks == nil || bd.Folders == nil || bd.Objects == nil {
+		t.Error("缺失的分组应升级为空列表")
+	}
+}
+
+func TestDecodeBackupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // 错误中应包含的文字
+	}{
+		{"不是 JSON", `hello`, errNotBackupFile.Error()},
+		{"其他 JSON", `{"name":"x"}`, errNotBackupFile.Error()},
+		{"版本过高", `{"schema_version": 99, "backup_time": "t", "format": "simplified"}`, "高于当前支持的版本"},
+		{"版本无效", `{"schema_version": "2"}`, "备份格式版本无效"},
+		{"分组不是列表", `{"backup_time": "t", "format": "simplified", "rules": {}}`, "rules 应为列表"},
+		{"缺少 serializedModel", `{"schema_version": 2, "backup_time": "t", "format": "simplified", "mcp_servers": [{"format": "JsonMCPServer"}]}`, "第 1 个对象缺少 serializedModel"},
+		{"文件夹缺少 uid", `{"schema_version": 2, "backup_time": "t", "format": "simplified", "folders": [{"name": "F"}]}`, "第 1 个文件夹缺少 uid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeBackup([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("decodeBackup 错误 = %v，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := decodeBackup([]byte(`[]`)); !errors.Is(err, errNotBackupFile) {
+		t.Errorf("顶层为列表时应返回 errNotBackupFile，实际 %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 服务端地址：Warp GraphQL 与 Firebase 令牌接口的地址和 API key 可通过设置文件或环境变量覆盖
+// （环境变量优先），用于本地模拟服务、公司出口代理或预发环境。程序启动后首次请求时读取一次。
+
+const (
+	defaultGraphQLURL       = "https://app.warp.dev/graphql/v2"
+	defaultFirebaseTokenURL = "https://securetoken.googleapis.com/v1/token"
+
+	envGraphQLURL       = "WARPMINI_GRAPHQL_URL"
+	envFirebaseTokenURL = "WARPMINI_FIREBASE_TOKEN_URL"
+	envFirebaseAPIKey   = "WARPMINI_FIREBASE_API_KEY"
+)
+
+// apiEndpoints 实际使用的服务端地址
+type apiEndpoints struct {
+	GraphQLURL       string
+	FirebaseTokenURL string
+	FirebaseAPIKey   string
+}
+
+var (
+	endpointsOnce sync.Once
+	endpoints     apiEndpoints
+	endpointsErr  error
+)
+
+// currentEndpoints 返回服务端地址（结果缓存）
+func currentEndpoints() (apiEndpoints, error) {
+	endpointsOnce.Do(func() {
+		s, err := loadSettings()
+		if err != nil {
+			endpointsErr = err
+			return
+		}
+		endpoints, endpointsErr = resolveEndpoints(s, os.Getenv)
+	})
+	return endpoints, endpointsErr
+}
+
+// resolveEndpoints 按 环境变量 > 设置文件 > 默认值 的顺序确定地址，并校验 URL
+func resolveEndpoints(s appSettings, getenv func(string) string) (apiEndpoints, error) {
+	pick := func(env, setting, def string) string {
+		if v := strings.TrimSpace(getenv(env)); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(setting); v != "" {
+			return v
+		}
+		return def
+	}
+	e := apiEndpoints{
+		GraphQLURL:       pick(envGraphQLURL, s.GraphQLURL, defaultGraphQLURL),
+		FirebaseTokenURL: pick(envFirebaseTokenURL, s.FirebaseTokenURL, defaultFirebaseTokenURL),
+		FirebaseAPIKey:   pick(envFirebaseAPIKey, s.FirebaseAPIKey, firebaseAPIKey),
+	}
+	for _, c := range []struct{ name, raw string }{
+		{"GraphQL 地址", e.GraphQLURL},
+		{"Firebase 令牌地址", e.FirebaseTokenURL},
+	} {
+		u, err := url.Parse(c.raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return e, fmt.Errorf("%s 无效: %q", c.name, c.raw)
+		}
+	}
+	return e, nil
+}
+
+// withQuery 在地址上追加查询参数，保留地址中已有的参数
+func withQuery(base, key, value string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
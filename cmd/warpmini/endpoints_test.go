@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// envMap 用 map 模拟环境变量
+func envMap(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	file := appSettings{GraphQLURL: "https://file.example/graphql", FirebaseAPIKey: "file-key"}
+	tests := []struct {
+		name     string
+		settings appSettings
+		env      map[string]string
+		want     apiEndpoints
+	}{
+		{"默认值", appSettings{}, nil, apiEndpoints{defaultGraphQLURL, defaultFirebaseTokenURL, firebaseAPIKey}},
+		{"设置文件", file, nil, apiEndpoints{"https://file.example/graphql", defaultFirebaseTokenURL, "file-key"}},
+		{
+			"环境变量优先",
+			file,
+			map[string]string{envGraphQLURL: " http://127.0.0.1:8080/graphql ", envFirebaseTokenURL: "http://127.0.0.1:9099/token"},
+			apiEndpoints{"http://127.0.0.1:8080/graphql", "http://127.0.0.1:9099/token", "file-key"},
+		},
+		{"空白的环境变量不覆盖", file, map[string]string{envFirebaseAPIKey: "  "}, apiEndpoints{"https://file.example/graphql", defaultFirebaseTokenURL, "file-key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveEndpoints(tt.settings, envMap(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveEndpoints = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEndpointsRejectsInvalidURL(t *testing.T) {
+	for _, tt := range []struct {
+		env  string
+		url  string
+		want string
+	}{
+		{envGraphQLURL, "app.warp.dev/graphql", "GraphQL 地址 无效"},
+		{envGraphQLURL, "ftp://app.warp.dev/graphql", "GraphQL 地址 无效"},
+		{envFirebaseTokenURL, "https://", "Firebase 令牌地址 无效"},
+		{envFirebaseTokenURL, "http://[::1", "Firebase 令牌地址 无效"},
+	} {
+		_, err := resolveEndpoints(appSettings{}, envMap(map[string]string{tt.env: tt.url}))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s=%q: 错误 = %v，期望包含 %q", tt.env, tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	if got := withQuery("https://x.example/token?alt=json", "key", "a b"); got != "https://x.example/token?alt=json&key=a+b" {
+		t.Errorf("withQuery = %s", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Warp Drive 文件夹树：备份时记录每个文件夹的 uid、名称和父文件夹，
+// 恢复时按层级重建（见 executeRestorePlan），并把旧 uid 映射到新账号中的 uid，
+// 使对象回到原来的文件夹。
+
+// folderPaths 根据 uid -> 名称/父 uid 计算每个文件夹的完整路径（如 "工作/脚本"）
+func folderPaths(folders []map[string]any) map[string]string {
+	byUID := make(map[string]map[string]any, len(folders))
+	for _, f := range folders {
+		if uid := asString(f["uid"]); uid != "" {
+			byUID[uid] = f
+		}
+	}
+	paths := make(map[string]string, len(byUID))
+	var resolve func(uid string, seen map[string]bool) string
+	resolve = func(uid string, seen map[string]bool) string {
+		if p, ok := paths[uid]; ok {
+			return p
+		}
+		f := byUID[uid]
+		if f == nil || seen[uid] {
+			return ""
+		}
+		seen[uid] = true
+		name := asString(f["name"])
+		p := name
+		if parent := resolve(asString(f["folderId"]), seen); parent != "" {
+			p = parent + "/" + name
+		}
+		paths[uid] = p
+		return p
+	}
+	for uid := range byUID {
+		resolve(uid, map[string]bool{})
+	}
+	return paths
+}
+
+// sortFoldersByDepth 返回按层级排序的文件夹（父文件夹在前），保证创建顺序正确
+func sortFoldersByDepth(folders []map[string]any) []map[string]any {
+	paths := folderPaths(folders)
+	sorted := append([]map[string]any(nil), folders...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		pi, pj := paths[asString(sorted[i]["uid"])], paths[asString(sorted[j]["uid"])]
+		di, dj := strings.Count(pi, "/"), strings.Count(pj, "/")
+		if di != dj {
+			return di < dj
+		}
+		return pi < pj
+	})
+	return sorted
+}
+
+// cloudFolders 把云端返回的文件夹转换为备份使用的扁平结构
+func cloudFolders(cloud updatedCloudObjects) []map[string]any {
+	folders := []map[string]any{}
+	for _, f := range cloud.Folders {
+		folders = append(folders, map[string]any{
+			"uid":                   f.Metadata.UID,
+			"name":                  f.Name,
+			"folderId":              f.Metadata.FolderID,
+			"metadataLastUpdatedTs": string(f.Metadata.MetadataLastUpdatedTs),
+		})
+	}
+	return folders
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// genericStringObject 格式注册表：每种已知格式各自负责取名称和生成去重键。
+// 未登记的格式在备份中按原样保留，恢复时按内容去重。
+
+const (
+	formatMCPServer = "JsonMCPServer"
+	formatAIFact    = "JsonAIFact"
+)
+
+// objectFormat 描述一种已知的 genericStringObject 格式
+type objectFormat struct {
+	Name      string // Warp 中的 format 值
+	Label     string // 界面展示名
+	KeyPrefix string // 去重键前缀
+	NameOf    func(model map[string]any) string
+	SetName   func(model map[string]any, name string) // 重命名副本时使用
+	// 比较内容时忽略的顶层字段：如导入 mcp.json 时每次新生成的 uuid，
+	// 不忽略的话同一配置导入两次、或与云端已有的相同配置比较时都会被当作不同内容
+	IgnoreKeys []string
+}
+
+var knownFormats = []objectFormat{
+	{
+		Name:      formatMCPServer,
+		Label:     "MCP",
+		KeyPrefix: "mcp",
+		NameOf: func(model map[string]any) string {
+			name, _ := model["name"].(string)
+			return name
+		},
+		SetName: func(model map[string]any, name string) {
+			model["name"] = name
+		},
+		IgnoreKeys: []string{"uuid"},
+	},
+	{
+		Name:      formatAIFact,
+		Label:     "规则",
+		KeyPrefix: "rule",
+		NameOf: func(model map[string]any) string {
+			memory, _ := model["memory"].(map[string]any)
+			name, _ := memory["name"].(string)
+			return name
+		},
+		SetName: func(model map[string]any, name string) {
+			if memory, ok := model["memory"].(map[string]any); ok {
+				memory["name"] = name
+			}
+		},
+	},
+	{
+		Name:      "JsonEnvVarCollection",
+		Label:     "环境变量集",
+		KeyPrefix: "envvars",
+		NameOf: func(model map[string]any) string {
+			title, _ := model["title"].(string)
+			return title
+		},
+		SetName: func(model map[string]any, name string) {
+			model["title"] = name
+		},
+	},
+}
+
+func lookupFormat(name string) (objectFormat, bool) {
+	for _, f := range knownFormats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return objectFormat{}, false
+}
+
+// formatLabel 返回格式的展示名，未知格式直接使用 format 值
+func formatLabel(name string) string {
+	if f, ok := lookupFormat(name); ok {
+		return f.Label
+	}
+	return name
+}
+
+// objectName 解析 serializedModel 得到对象名称，未知格式或解析失败时为空
+func objectName(format, serialized string) string {
+	f, ok := lookupFormat(format)
+	if !ok {
+		return ""
+	}
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return ""
+	}
+	return f.NameOf(model)
+}
+
+// renameObject 修改 serializedModel 中的名称，未知格式或解析失败时返回错误
+func renameObject(format, serialized, name string) (string, error) {
+	f, ok := lookupFormat(format)
+	if !ok || f.SetName == nil {
+		return "", fmt.Errorf("格式 %s 不支持重命名", format)
+	}
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return "", err
+	}
+	f.SetName(model, name)
+	b, err := json.Marshal(model)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// objectDedupeKey 生成去重键：已知格式按名称（如 mcp:<name>），未知格式按内容哈希。
+// 已知格式但取不到名称时返回空，表示不做去重。
+func objectDedupeKey(format, serialized string) string {
+	if f, ok := lookupFormat(format); ok {
+		if name := objectName(format, serialized); name != "" {
+			return fmt.Sprintf("%s:%s", f.KeyPrefix, name)
+		}
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", format, objectContentHash(format, serialized))
+}
+
+// canonicalJSON 规范化 JSON 文本（键排序、去空白），非 JSON 内容原样返回
+func canonicalJSON(s string) string {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return strings.TrimSpace(s)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return string(b)
+}
+
+// contentHash 对规范化后的内容计算 sha256
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(canonicalJSON(s)))
+	return hex.EncodeToString(sum[:])
+}
+
+// objectContentHash 按格式计算 serializedModel 的内容哈希，忽略 IgnoreKeys 中的字段
+func objectContentHash(format, serialized string) string {
+	f, ok := lookupFormat(format)
+	if !ok || len(f.IgnoreKeys) == 0 {
+		return contentHash(serialized)
+	}
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return contentHash(serialized)
+	}
+	for _, k := range f.IgnoreKeys {
+		delete(model, k)
+	}
+	b, err := json.Marshal(model)
+	if err != nil {
+		return contentHash(serialized)
+	}
+	return contentHash(string(b))
+}
+
+// uniquenessKey 创建 genericStringObject 时的 uniquenessKey：由格式和规范化后的内容决定，
+// 同一内容重复创建时服务端返回 UniqueKeyConflict，中断后重新恢复不会产生重复对象
+func uniquenessKey(format, serializedModel string) string {
+	return "warpmini:" + format + ":" + objectContentHash(format, serializedModel)
+}
+
+// genericObjects 返回备份中全部 genericStringObject（MCP、规则及其他格式）。
+// mcp_servers / rules 中缺少 format 的条目按所在分组补全。
+func (b BackupData) genericObjects() []map[string]any {
+	all := make([]map[string]any, 0, len(b.MCPServers)+len(b.Rules)+len(b.Objects))
+	withFormat := func(m map[string]any, format string) map[string]any {
+		if asString(m["format"]) != "" {
+			return m
+		}
+		c := make(map[string]any, len(m)+1)
+		for k, v := range m {
+			c[k] = v
+		}
+		c["format"] = format
+		return c
+	}
+	for _, m := range b.MCPServers {
+		all = append(all, withFormat(m, formatMCPServer))
+	}
+	for _, m := range b.Rules {
+		all = append(all, withFormat(m, formatAIFact))
+	}
+	all = append(all, b.Objects...)
+	return all
+}
+
+// summarizeBackup 按格式统计备份内容，用于界面提示
+func summarizeBackup(b BackupData) string {
+	counts := map[string]int{}
+	for _, m := range b.genericObjects() {
+		counts[asString(m["format"])]++
+	}
+	var parts []string
+	// 已知格式按注册顺序在前，其余按名称排序
+	for _, f := range knownFormats {
+		if n := counts[f.Name]; n > 0 || f.Name == formatMCPServer || f.Name == formatAIFact {
+			parts = append(parts, fmt.Sprintf("%s %d", f.Label, n))
+		}
+		delete(counts, f.Name)
+	}
+	others := make([]string, 0, len(counts))
+	for name := range counts {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		parts = append(parts, fmt.Sprintf("%s %d", name, counts[name]))
+	}
+	parts = append(parts,
+		fmt.Sprintf("工作流 %d", len(b.Workflows)),
+		fmt.Sprintf("笔记本 %d", len(b.Notebooks)),
+		fmt.Sprintf("文件夹 %d", len(b.Folders)),
+	)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// GraphQL 请求与响应的类型定义。响应按操作解码为具体结构，
+// 结果联合类型（<Op>Output / UserFacingError）通过 __typename 区分；
+// 字段缺失或类型不符时，错误信息给出完整字段路径，如 data.createFolder.folder.metadata.uid。
+
+// ===== 请求 =====
+
+// gqlRequest GraphQL 请求体
+type gqlRequest struct {
+	OperationName string `json:"operationName"`
+	Variables     any    `json:"variables"`
+	Query         string `json:"query"`
+}
+
+// gqlVariables 所有操作共用的变量结构
+type gqlVariables[T any] struct {
+	Input          T              `json:"input"`
+	RequestContext requestContext `json:"requestContext"`
+}
+
+type requestContext struct {
+	ClientContext clientContext `json:"clientContext"`
+	OSContext     osContext     `json:"osContext"`
+}
+
+type clientContext struct {
+	Version string `json:"version"`
+}
+
+type osContext struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+}
+
+// newRequestContext 模拟 Warp 客户端的请求上下文
+func newRequestContext() requestContext {
+	return requestContext{
+		ClientContext: clientContext{Version: "v0.2025.09.03.08.11.stable_02"},
+		OSContext:     osContext{Category: runtime.GOOS, Name: runtime.GOOS},
+	}
+}
+
+// updatedCloudObjectsInput 各类对象的本地已知版本；全部为空且 forceRefresh 时返回全部对象，
+// 否则只返回新增对象和版本比已知版本新的对象（见 incremental_backup.go）
+type updatedCloudObjectsInput struct {
+	Folders              []objectVersionInput `json:"folders"`
+	ForceRefresh         bool                 `json:"forceRefresh"`
+	GenericStringObjects []objectVersionInput `json:"genericStringObjects"`
+	Notebooks            []objectVersionInput `json:"notebooks"`
+	Workflows            []objectVersionInput `json:"workflows"`
+}
+
+// objectVersionInput 本地已知的对象版本
+type objectVersionInput struct {
+	UID                   string `json:"uid"`
+	MetadataLastUpdatedTs string `json:"metadataLastUpdatedTs"`
+}
+
+type ownerInput struct {
+	UID  string `json:"uid"`
+	Type string `json:"type"`
+}
+
+func userOwner(uid string) ownerInput {
+	return ownerInput{UID: uid, Type: "User"}
+}
+
+type genericStringObjectInput struct {
+	ClientID        string  `json:"clientId"`
+	Entrypoint      string  `json:"entrypoint"`
+	Format          string  `json:"format"`
+	InitialFolderID *string `json:"initialFolderId"`
+	SerializedModel string  `json:"serializedModel"`
+	UniquenessKey   *string `json:"uniquenessKey"`
+}
+
+type createGenericStringObjectInput struct {
+	GenericStringObject genericStringObjectInput `json:"genericStringObject"`
+	Owner               ownerInput               `json:"owner"`
+}
+
+type workflowInput struct {
+	ClientID        string  `json:"clientId"`
+	Data            string  `json:"data"`
+	Entrypoint      string  `json:"entrypoint"`
+	InitialFolderID *string `json:"initialFolderId"`
+}
+
+type createWorkflowInput struct {
+	Workflow workflowInput `json:"workflow"`
+	Owner    ownerInput    `json:"owner"`
+}
+
+type notebookInput struct {
+	ClientID        string  `json:"clientId"`
+	Title           string  `json:"title"`
+	Data            string  `json:"data"`
+	Entrypoint      string  `json:"entrypoint"`
+	InitialFolderID *string `json:"initialFolderId"`
+}
+
+type createNotebookInput struct {
+	Notebook notebookInput `json:"notebook"`
+	Owner    ownerInput    `json:"owner"`
+}
+
+type folderInput struct {
+	ClientID        string  `json:"clientId"`
+	Name            string  `json:"name"`
+	InitialFolderID *string `json:"initialFolderId"`
+}
+
+type createFolderInput struct {
+	Folder folderInput `json:"folder"`
+	Owner  ownerInput  `json:"owner"`
+}
+
+type updateGenericStringObjectInput struct {
+	UID             string `json:"uid"`
+	SerializedModel string `json:"serializedModel"`
+}
+
+type updateWorkflowInput struct {
+	UID  string `json:"uid"`
+	Data string `json:"data"`
+}
+
+type updateNotebookInput struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	Data  string `json:"data"`
+}
+
+type trashObjectInput struct {
+	UID string `json:"uid"`
+}
+
+// optionalString 空字符串编码为 null
+func optionalString(s string) *string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return &s
+}
+
+// ===== 响应 =====
+
+// gqlError 响应顶层 errors 中的一项
+type gqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+// gqlUserError UserFacingError.error，Typename 为具体错误类型（如 UniqueKeyConflict），见 api_errors.go
+type gqlUserError struct {
+	Typename string `json:"__typename"`
+	Message  string `json:"message"`
+}
+
+// unionHeader 结果联合类型的公共部分
+type unionHeader struct {
+	Typename string        `json:"__typename"`
+	Error    *gqlUserError `json:"error"`
+}
+
+// check 校验 __typename：期望的输出类型返回 nil，UserFacingError 返回 *apiError
+func (h unionHeader) check(field, want string) error {
+	switch h.Typename {
+	case want:
+		return nil
+	case "UserFacingError":
+		if h.Error == nil {
+			return fmt.Errorf("响应缺少字段 data.%s.error", field)
+		}
+		return h.Error.apiError()
+	case "":
+		return fmt.Errorf("响应缺少字段 data.%s.__typename", field)
+	default:
+		return fmt.Errorf("响应字段 data.%s.__typename 为 %q，期望 %s 或 UserFacingError", field, h.Typename, want)
+	}
+}
+
+// objectMetadata 云端对象的元数据
+type objectMetadata struct {
+	UID                   string       `json:"uid"`
+	MetadataLastUpdatedTs gqlTimestamp `json:"metadataLastUpdatedTs"`
+	FolderID              string       `json:"folderId"`
+}
+
+type cloudGenericStringObject struct {
+	Format          string         `json:"format"`
+	SerializedModel string         `json:"serializedModel"`
+	Metadata        objectMetadata `json:"metadata"`
+}
+
+type cloudWorkflow struct {
+	Data     gqlJSONText    `json:"data"`
+	Metadata objectMetadata `json:"metadata"`
+}
+
+type cloudNotebook struct {
+	Title    string         `json:"title"`
+	Data     string         `json:"data"`
+	Metadata objectMetadata `json:"metadata"`
+}
+
+type cloudFolder struct {
+	Name     string         `json:"name"`
+	Metadata objectMetadata `json:"metadata"`
+}
+
+// updatedCloudObjects UpdatedCloudObjectsOutput 中的对象列表
+type updatedCloudObjects struct {
+	GenericStringObjects []cloudGenericStringObject `json:"genericStringObjects"`
+	Workflows            []cloudWorkflow            `json:"workflows"`
+	Notebooks            []cloudNotebook            `json:"notebooks"`
+	Folders              []cloudFolder              `json:"folders"`
+
+	Skipped []string `json:"-"` // 缺少必填字段而被忽略的对象路径，见 dropInvalid
+}
+
+type updatedCloudObjectsResult struct {
+	unionHeader
+	updatedCloudObjects
+}
+
+// dropInvalid 去掉缺少必填字段的对象，返回其余对象；去掉的对象记入 Skipped，给出完整路径，
+// 如 data.updatedCloudObjects.genericStringObjects[3].serializedModel。
+// 单个对象损坏不应让整次备份或恢复失败，外层结构（__typename）仍由 check 严格校验
+func (o updatedCloudObjects) dropInvalid(field string) updatedCloudObjects {
+	out := updatedCloudObjects{Skipped: append([]string(nil), o.Skipped...)}
+	skip := func(list string, i int, name string) {
+		out.Skipped = append(out.Skipped, fmt.Sprintf("data.%s.%s[%d].%s", field, list, i, name))
+	}
+	for i, g := range o.GenericStringObjects {
+		switch {
+		case g.Format == "":
+			skip("genericStringObjects", i, "format")
+		case g.SerializedModel == "":
+			skip("genericStringObjects", i, "serializedModel")
+		case g.Metadata.UID == "":
+			skip("genericStringObjects", i, "metadata.uid")
+		default:
+			out.GenericStringObjects = append(out.GenericStringObjects, g)
+		}
+	}
+	for i, w := range o.Workflows {
+		switch {
+		case w.Data == "":
+			skip("workflows", i, "data")
+		case w.Metadata.UID == "":
+			skip("workflows", i, "metadata.uid")
+		default:
+			out.Workflows = append(out.Workflows, w)
+		}
+	}
+	for i, n := range o.Notebooks {
+		if n.Metadata.UID == "" {
+			skip("notebooks", i, "metadata.uid")
+			continue
+		}
+		out.Notebooks = append(out.Notebooks, n)
+	}
+	for i, f := range o.Folders {
+		if f.Metadata.UID == "" {
+			skip("folders", i, "metadata.uid")
+			continue
+		}
+		out.Folders = append(out.Folders, f)
+	}
+	return out
+}
+
+// skippedObjectsNote 列出因缺少字段而被忽略的云端对象，附加到备份结果或恢复计划摘要后
+func skippedObjectsNote(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("；已忽略 %d 个缺少字段的云端对象：%s", len(paths), strings.Join(paths, "，"))
+}
+
+// createdObject create 类 mutation 返回的新对象，只关心 uid
+type createdObject struct {
+	Metadata objectMetadata `json:"metadata"`
+}
+
+// createResult create 类 mutation 的结果
+type createResult struct {
+	unionHeader
+	GenericStringObject *createdObject `json:"genericStringObject"`
+	Workflow            *createdObject `json:"workflow"`
+	Notebook            *createdObject `json:"notebook"`
+	Folder              *createdObject `json:"folder"`
+}
+
+// updateResult update 类 mutation 的结果
+type updateResult struct {
+	unionHeader
+}
+
+// gqlTimestamp 兼容字符串、数字和 null 的时间戳
+type gqlTimestamp string
+
+func (t *gqlTimestamp) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*t = ""
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*t = gqlTimestamp(s)
+	default:
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		*t = gqlTimestamp(n.String())
+	}
+	return nil
+}
+
+// gqlJSONText 内容为 JSON 文本的字段：服务端可能返回字符串，也可能直接返回对象
+type gqlJSONText string
+
+func (t *gqlJSONText) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*t = ""
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*t = gqlJSONText(s)
+	default:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, b); err != nil {
+			return err
+		}
+		*t = gqlJSONText(buf.String())
+	}
+	return nil
+}
+
+// decodeGQLResponse 把响应中的 data.<field> 解码到 out
+func decodeGQLResponse(body []byte, field string, out any) error {
+	var r struct {
+		Data   map[string]json.RawMessage `json:"data"`
+		Errors []gqlError                 `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("响应不是合法的 JSON: %w", err)
+	}
+	raw, ok := r.Data[field]
+	if !ok || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		if len(r.Errors) > 0 {
+			return gqlErrors(r.Errors)
+		}
+		if r.Data == nil {
+			return errors.New("响应缺少字段 data")
+		}
+		return fmt.Errorf("响应缺少字段 data.%s", field)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("响应字段 data.%s.%s 类型不符: 期望 %s，实际为 %s", field, typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("解析响应字段 data.%s 失败: %w", field, err)
+	}
+	return nil
+}
+
+// gqlErrors 合并响应顶层的 errors
+func gqlErrors(errs []gqlError) error {
+	return errors.New("GraphQL 错误: " + joinGQLErrors(errs))
+}
+
+func joinGQLErrors(errs []gqlError) string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msg := e.Message
+		if len(e.Path) > 0 {
+			msg += fmt.Sprintf("（%v）", e.Path)
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// httpStatusError 非 2xx 响应的错误，响应体带有 GraphQL errors 时一并给出
+func httpStatusError(code int, body []byte) error {
+	e := &apiError{Status: code}
+	switch code {
+	case http.StatusUnauthorized:
+		e.Kind = apiErrTokenExpired
+	case http.StatusTooManyRequests:
+		e.Kind = apiErrRateLimited
+	}
+	var r struct {
+		Errors []gqlError `json:"errors"`
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Errors) > 0 {
+		e.Detail = joinGQLErrors(r.Errors)
+	}
+	return e
+}
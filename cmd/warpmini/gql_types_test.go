@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdatedCloudObjectsDropInvalid(t *testing.T) {
+	const meta = `"metadata":{"uid":"u","metadataLastUpdatedTs":"1"}`
+	tests := []struct {
+		name    string
+		body    string
+		kept    int      // 保留的对象数
+		skipped []string // 期望被忽略的对象路径
+	}{
+		{
+			"完整",
+			`{"genericStringObjects":[{"format":"JsonMCPServer","serializedModel":"{}",` + meta + `}],"workflows":[{"data":{"name":"w"},` + meta + `}],"notebooks":[{"title":"",` + meta + `}],"folders":[{"name":"F",` + meta + `}]}`,
+			4, nil,
+		},
+		{
+			"缺少 serializedModel",
+			`{"genericStringObjects":[{"format":"JsonMCPServer","serializedModel":"{}",` + meta + `},{"format":"JsonAIFact",` + meta + `}]}`,
+			1, []string{"data.updatedCloudObjects.genericStringObjects[1].serializedModel"},
+		},
+		{
+			"缺少 format",
+			`{"genericStringObjects":[{"serializedModel":"{}",` + meta + `}]}`,
+			0, []string{"data.updatedCloudObjects.genericStringObjects[0].format"},
+		},
+		{
+			"缺少 metadata",
+			`{"genericStringObjects":[{"format":"JsonMCPServer","serializedModel":"{}"}]}`,
+			0, []string{"data.updatedCloudObjects.genericStringObjects[0].metadata.uid"},
+		},
+		{
+			"工作流缺少 data",
+			`{"workflows":[{` + meta + `},{"data":{"name":"w"},` + meta + `}]}`,
+			1, []string{"data.updatedCloudObjects.workflows[0].data"},
+		},
+		{
+			"多处缺失",
+			`{"notebooks":[{"title":"n"}],"folders":[{"name":"F","metadata":{}},{"name":"G",` + meta + `}]}`,
+			1, []string{"data.updatedCloudObjects.notebooks[0].metadata.uid", "data.updatedCloudObjects.folders[0].metadata.uid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"data":{"updatedCloudObjects":` + tt.body + `}}`
+			var out updatedCloudObjects
+			if err := decodeGQLResponse([]byte(body), "updatedCloudObjects", &out); err != nil {
+				t.Fatal(err)
+			}
+			got := out.dropInvalid("updatedCloudObjects")
+			kept := len(got.GenericStringObjects) + len(got.Workflows) + len(got.Notebooks) + len(got.Folders)
+			if kept != tt.kept || !reflect.DeepEqual(got.Skipped, tt.skipped) {
+				t.Errorf("保留 %d、忽略 %v，期望 %d、%v", kept, got.Skipped, tt.kept, tt.skipped)
+			}
+		})
+	}
+}
+
+// 被忽略的对象出现在备份和恢复计划的摘要中
+func TestSkippedCloudObjectsReported(t *testing.T) {
+	cloud := updatedCloudObjects{GenericStringObjects: []cloudGenericStringObject{
+		{Format: formatMCPServer, SerializedModel: `{"name":"a"}`, Metadata: objectMetadata{UID: "u1"}},
+		{Format: formatAIFact, Metadata: objectMetadata{UID: "u2"}},
+	}}.dropInvalid("updatedCloudObjects")
+	const path = "data.updatedCloudObjects.genericStringObjects[1].serializedModel"
+
+	bd := backupFromCloud(cloud, "uid", "a@example.com")
+	if len(bd.MCPServers) != 1 || len(bd.Rules) != 0 || !reflect.DeepEqual(bd.SkippedCloud, []string{path}) {
+		t.Errorf("备份 MCP %d、规则 %d、忽略 %v", len(bd.MCPServers), len(bd.Rules), bd.SkippedCloud)
+	}
+	plan := planRestore(BackupData{}, buildCloudInventory(cloud), restoreOptions{})
+	if !strings.Contains(plan.Summary(), path) {
+		t.Errorf("计划摘要中应列出被忽略的对象: %s", plan.Summary())
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+// 增量备份：备份中保留每个对象的 uid 和 metadataLastUpdatedTs，下次备份时把这些版本发给服务端
+// （forceRefresh: false），只取回此后新增或修改的对象，再与上一份备份合并为新的完整快照。
+// 服务端不返回已删除的对象，因此距上次全量获取（last_full_fetch，合并时沿用）超过 incrementalMaxAge
+// 时改为全量获取；云端已删除的对象最多在这段时间内的快照中残留。
+
+const incrementalMaxAge = 24 * time.Hour
+
+var errNoBackup = errors.New("该账号还没有备份")
+
+// 备份条目中记录云端元数据的键
+const (
+	metaUIDKey = "uid"
+	metaTsKey  = "metadataLastUpdatedTs"
+)
+
+// latestAccountBackup 读取账号最新的一份备份
+func latestAccountBackup(email, passphrase string) (BackupData, error) {
+	root, err := backupsRootDir()
+	if err != nil {
+		return BackupData{}, err
+	}
+	entries, err := listAccountBackups(filepath.Join(root, accountDirName(email)), "")
+	if err != nil {
+		return BackupData{}, err
+	}
+	if len(entries) == 0 {
+		return BackupData{}, errNoBackup
+	}
+	return loadBackupFile(entries[0].Path, passphrase)
+}
+
+// incrementalBase 返回可作为增量基础的上一份备份，不可用时返回 false
+func incrementalBase(userID, email, passphrase string, now time.Time) (BackupData, bool) {
+	bd, err := latestAccountBackup(email, passphrase)
+	if err != nil || !usableIncrementalBase(bd, userID, now) {
+		return BackupData{}, false
+	}
+	return bd, true
+}
+
+// usableIncrementalBase 备份能否作为增量基础：同一账号的完整备份、未脱敏、
+// 距上次全量获取未超过 incrementalMaxAge，且每个条目都记录了 uid 和 metadataLastUpdatedTs
+func usableIncrementalBase(bd BackupData, userID string, now time.Time) bool {
+	if bd.BackupType != "global" || len(bd.RedactedSecrets) > 0 {
+		return false
+	}
+	if bd.AccountUID != "" && userID != "" && bd.AccountUID != userID {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, bd.LastFullFetch)
+	if err != nil || now.Sub(t) > incrementalMaxAge || now.Before(t) {
+		return false
+	}
+	for _, group := range [][]map[string]any{bd.genericObjects(), bd.Workflows, bd.Notebooks, bd.Folders} {
+		for _, m := range group {
+			if asString(m[metaUIDKey]) == "" || asString(m[metaTsKey]) == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// knownVersions 上一份备份中各对象的版本，作为增量请求的输入
+func knownVersions(base BackupData) updatedCloudObjectsInput {
+	versions := func(group []map[string]any) []objectVersionInput {
+		out := make([]objectVersionInput, 0, len(group))
+		for _, m := range group {
+			out = append(out, objectVersionInput{UID: asString(m[metaUIDKey]), MetadataLastUpdatedTs: asString(m[metaTsKey])})
+		}
+		return out
+	}
+	return updatedCloudObjectsInput{
+		Folders:              versions(base.Folders),
+		ForceRefresh:         false,
+		GenericStringObjects: versions(base.genericObjects()),
+		Notebooks:            versions(base.Notebooks),
+		Workflows:            versions(base.Workflows),
+	}
+}
+
+// mergeIncremental 把增量结果合并到上一份备份：同 uid 的条目替换，新条目追加在后，其余沿用；
+// 上次全量获取的时间沿用上一份备份的
+func mergeIncremental(base, changed BackupData) BackupData {
+	merged := changed
+	merged.MCPServers = mergeEntries(base.MCPServers, changed.MCPServers)
+	merged.Rules = mergeEntries(base.Rules, changed.Rules)
+	merged.Objects = mergeEntries(base.Objects, changed.Objects)
+	merged.Workflows = mergeEntries(base.Workflows, changed.Workflows)
+	merged.Notebooks = mergeEntries(base.Notebooks, changed.Notebooks)
+	merged.Folders = mergeEntries(base.Folders, changed.Folders)
+	merged.Incremental = true
+	merged.LastFullFetch = base.LastFullFetch
+	return merged
+}
+
+func mergeEntries(base, changed []map[string]any) []map[string]any {
+	byUID := make(map[string]map[string]any, len(changed))
+	for _, m := range changed {
+		byUID[asString(m[metaUIDKey])] = m
+	}
+	out := make([]map[string]any, 0, len(base)+len(changed))
+	used := map[string]bool{}
+	for _, m := range base {
+		uid := asString(m[metaUIDKey])
+		if c, ok := byUID[uid]; ok {
+			out = append(out, c)
+			used[uid] = true
+			continue
+		}
+		out = append(out, m)
+	}
+	for _, m := range changed {
+		if uid := asString(m[metaUIDKey]); !used[uid] {
+			out = append(out, m)
+			used[uid] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func mcpEntry(uid, ts, model string) map[string]any {
+	return map[string]any{
+		"format":          formatMCPServer,
+		"serializedModel": model,
+		"folderId":        "",
+		metaUIDKey:        uid,
+		metaTsKey:         ts,
+	}
+}
+
+// fetchedAt 模拟在 at 时刻从云端获取到的备份
+func fetchedAt(at time.Time, mcp ...map[string]any) BackupData {
+	stamp := at.Format(time.RFC3339)
+	return BackupData{
+		BackupTime:    stamp,
+		LastFullFetch: stamp,
+		BackupType:    "global",
+		MCPServers:    mcp,
+		AccountUID:    "u1",
+	}
+}
+
+func TestMergeIncremental(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := fetchedAt(t0, mcpEntry("a", "1", `{"name":"a"}`), mcpEntry("b", "1", `{"name":"b"}`))
+	changed := fetchedAt(t0.Add(time.Hour), mcpEntry("b", "2", `{"name":"b","v":2}`), mcpEntry("c", "2", `{"name":"c"}`))
+
+	merged := mergeIncremental(base, changed)
+	var got []string
+	for _, m := range merged.MCPServers {
+		got = append(got, asString(m[metaUIDKey])+"@"+asString(m[metaTsKey]))
+	}
+	want := []string{"a@1", "b@2", "c@2"}
+	if len(got) != len(want) {
+		t.Fatalf("合并结果 %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("合并结果 %v，期望 %v", got, want)
+		}
+	}
+	if !merged.Incremental || merged.BackupTime != changed.BackupTime {
+		t.Errorf("合并后 Incremental=%v BackupTime=%s", merged.Incremental, merged.BackupTime)
+	}
+}
+
+// 连续增量合并不能刷新上次全量获取的时间，否则永远不会重新全量获取，云端删除的对象会一直残留
+func TestChainedIncrementalKeepsLastFullFetch(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	full := fetchedAt(t0, mcpEntry("a", "1", `{"name":"a"}`))
+
+	first := mergeIncremental(full, fetchedAt(t0.Add(12*time.Hour), mcpEntry("b", "2", `{"name":"b"}`)))
+	if !usableIncrementalBase(first, "u1", t0.Add(20*time.Hour)) {
+		t.Fatal("20 小时内应可增量")
+	}
+	second := mergeIncremental(first, fetchedAt(t0.Add(20*time.Hour), mcpEntry("c", "3", `{"name":"c"}`)))
+	if second.LastFullFetch != full.LastFullFetch {
+		t.Fatalf("LastFullFetch = %s，期望沿用 %s", second.LastFullFetch, full.LastFullFetch)
+	}
+	if usableIncrementalBase(second, "u1", t0.Add(25*time.Hour)) {
+		t.Error("距上次全量获取超过 24 小时，应改为全量获取")
+	}
+}
+
+func TestUsableIncrementalBase(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0.Add(time.Hour)
+	ok := fetchedAt(t0, mcpEntry("a", "1", `{}`))
+
+	noFullFetch := ok
+	noFullFetch.LastFullFetch = ""
+	redacted := ok
+	redacted.RedactedSecrets = []string{"WARPMINI_TOKEN"}
+	noUID := fetchedAt(t0, mcpEntry("", "1", `{}`))
+	imported := ok
+	imported.BackupType = "mcp_json"
+
+	tests := []struct {
+		name   string
+		bd     BackupData
+		userID string
+		want   bool
+	}{
+		{"可用", ok, "u1", true},
+		{"其他账号", ok, "u2", false},
+		{"旧备份没有全量获取时间", noFullFetch, "u1", false},
+		{"已脱敏", redacted, "u1", false},
+		{"条目缺少 uid", noUID, "u1", false},
+		{"导入的备份", imported, "u1", false},
+	}
+	for _, tt := range tests {
+		if got := usableIncrementalBase(tt.bd, tt.userID, now); got != tt.want {
+			t.Errorf("%s: usableIncrementalBase = %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
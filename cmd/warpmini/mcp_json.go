@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// mcp.json 互转：其他 MCP 客户端通用的 {"mcpServers": {"名称": {...}}} 与
+// Warp 的 JsonMCPServer（serializedModel 中 transport_type 为 CLIServer 或 ServerSentEvents）。
+// 导入结果作为一份普通备份保存，再走现有的恢复流程上传到 Warp。
+
+const (
+	mcpTransportCLI = "CLIServer"
+	mcpTransportSSE = "ServerSentEvents"
+)
+
+// mcpJSONServer mcp.json 中单个服务的配置
+type mcpJSONServer struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Cwd     string            `json:"cwd,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// mcpJSONFile mcp.json 文件结构
+type mcpJSONFile struct {
+	MCPServers map[string]mcpJSONServer `json:"mcpServers"`
+}
+
+// mcpServerFromModel 把 JsonMCPServer 的 serializedModel 转为 mcp.json 配置；
+// 兼容 transport_type 包裹和直接写在顶层两种结构
+func mcpServerFromModel(serialized string) (string, mcpJSONServer, error) {
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return "", mcpJSONServer{}, err
+	}
+	name := asString(model["name"])
+	if name == "" {
+		return "", mcpJSONServer{}, fmt.Errorf("MCP 配置缺少名称")
+	}
+	t := model
+	if transport, ok := model["transport_type"].(map[string]any); ok {
+		if cli, ok := transport[mcpTransportCLI].(map[string]any); ok {
+			t = cli
+		} else if sse, ok := transport[mcpTransportSSE].(map[string]any); ok {
+			t = sse
+		}
+	}
+	srv := mcpJSONServer{
+		Command: asString(t["command"]),
+		Args:    stringList(t["args"]),
+		Env:     stringMap(t["env"]),
+		Cwd:     asString(t["cwd"]),
+		URL:     asString(t["url"]),
+		Headers: stringMap(t["headers"]),
+	}
+	if srv.Command == "" && srv.URL == "" {
+		return name, srv, fmt.Errorf("MCP 配置 %s 缺少 command 或 url", name)
+	}
+	return name, srv, nil
+}
+
+// mcpModelFromServer 生成 JsonMCPServer 的 serializedModel
+func mcpModelFromServer(name string, srv mcpJSONServer) (string, error) {
+	var transport map[string]any
+	switch {
+	case srv.URL != "":
+		sse := map[string]any{"url": srv.URL}
+		if len(srv.Headers) > 0 {
+			sse["headers"] = srv.Headers
+		}
+		transport = map[string]any{mcpTransportSSE: sse}
+	case srv.Command != "":
+		cli := map[string]any{"command": srv.Command, "args": append([]string{}, srv.Args...)}
+		if len(srv.Env) > 0 {
+			cli["env"] = srv.Env
+		}
+		if srv.Cwd != "" {
+			cli["cwd"] = srv.Cwd
+		}
+		transport = map[string]any{mcpTransportCLI: cli}
+	default:
+		return "", fmt.Errorf("mcpServers.%s 缺少 command 或 url", name)
+	}
+	model := map[string]any{
+		"uuid":           uuid.New().String(),
+		"name":           name,
+		"transport_type": transport,
+	}
+	b, err := json.Marshal(model)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// exportMCPJSON 把备份中的 MCP 配置导出为 mcp.json，返回无法转换的条目说明
+func exportMCPJSON(bd BackupData) ([]byte, []string, error) {
+	out := mcpJSONFile{MCPServers: map[string]mcpJSONServer{}}
+	var skipped []string
+	for _, m := range bd.genericObjects() {
+		if asString(m["format"]) != formatMCPServer {
+			continue
+		}
+		name, srv, err := mcpServerFromModel(asString(m["serializedModel"]))
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		if _, dup := out.MCPServers[name]; dup {
+			skipped = append(skipped, fmt.Sprintf("MCP 配置 %s 重名，仅导出第一个", name))
+			continue
+		}
+		out.MCPServers[name] = srv
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, skipped, nil
+}
+
+// importMCPJSON 解析 mcp.json 并按名称顺序追加到 bd.MCPServers
+func importMCPJSON(data []byte, bd *BackupData) error {
+	var f mcpJSONFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("mcp.json 格式错误: %w", err)
+	}
+	if f.MCPServers == nil {
+		return fmt.Errorf("mcp.json 中没有 mcpServers")
+	}
+	names := make([]string, 0, len(f.MCPServers))
+	for name := range f.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		serialized, err := mcpModelFromServer(name, f.MCPServers[name])
+		if err != nil {
+			return err
+		}
+		bd.MCPServers = append(bd.MCPServers, map[string]any{
+			"format":          formatMCPServer,
+			"serializedModel": serialized,
+		})
+	}
+	return nil
+}
+
+func stringList(v any) []string {
+	arr, _ := v.([]any)
+	out := make([]string, 0, len(arr))
+	for _, it := range arr {
+		if s, ok := it.(string); ok {
+			out = append(out, s)
+		} else if it != nil {
+			out = append(out, fmt.Sprint(it))
+		}
+	}
+	return out
+}
+
+func stringMap(v any) map[string]string {
+	m, _ := v.(map[string]any)
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		} else if val != nil {
+			out[k] = fmt.Sprint(val)
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMCPJSONRoundTrip(t *testing.T) {
+	const input = `{"mcpServers":{
+		"github":{"command":"npx","args":["-y","@modelcontextprotocol/server-github"],"env":{"GITHUB_TOKEN":"x"}},
+		"remote":{"url":"https://mcp.example.com/sse","headers":{"Authorization":"Bearer y"}}
+	}}`
+	var bd BackupData
+	if err := importMCPJSON([]byte(input), &bd); err != nil {
+		t.Fatal(err)
+	}
+	if len(bd.MCPServers) != 2 {
+		t.Fatalf("导入 %d 个配置，期望 2", len(bd.MCPServers))
+	}
+	for _, m := range bd.MCPServers {
+		var model map[string]any
+		if err := json.Unmarshal([]byte(asString(m["serializedModel"])), &model); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := uuid.Parse(asString(model["uuid"])); err != nil {
+			t.Errorf("%s 的 uuid 无效: %v", model["name"], err)
+		}
+	}
+
+	out, skipped, err := exportMCPJSON(bd)
+	if err != nil || len(skipped) > 0 {
+		t.Fatalf("导出失败: %v %v", err, skipped)
+	}
+	var want, got mcpJSONFile
+	if err := json.Unmarshal([]byte(input), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("往返结果不一致:\n%s", out)
+	}
+}
+
+func TestImportMCPJSONErrors(t *testing.T) {
+	for _, input := range []string{`not json`, `{}`, `{"mcpServers":{"x":{}}}`} {
+		var bd BackupData
+		if err := importMCPJSON([]byte(input), &bd); err == nil {
+			t.Errorf("%s: 应当报错", input)
+		}
+	}
+}
+
+// 导入时生成的 uuid 不参与内容比较：与云端相同的配置跳过，重复导入也不会产生副本
+func TestImportedMCPMatchesCloud(t *testing.T) {
+	const input = `{"mcpServers":{"github":{"command":"npx","args":["-y","server-github"]}}}`
+	var first, second BackupData
+	if err := importMCPJSON([]byte(input), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := importMCPJSON([]byte(input), &second); err != nil {
+		t.Fatal(err)
+	}
+	model := asString(first.MCPServers[0]["serializedModel"])
+	if asString(second.MCPServers[0]["serializedModel"]) == model {
+		t.Fatal("两次导入的 uuid 应不同")
+	}
+	if uniquenessKey(formatMCPServer, model) != uniquenessKey(formatMCPServer, asString(second.MCPServers[0]["serializedModel"])) {
+		t.Error("uniquenessKey 不应受 uuid 影响")
+	}
+	cloud := updatedCloudObjects{GenericStringObjects: []cloudGenericStringObject{
+		{Format: formatMCPServer, SerializedModel: model, Metadata: objectMetadata{UID: "c1"}},
+	}}
+	for _, strategy := range conflictStrategies {
+		plan := planRestore(second, buildCloudInventory(cloud), restoreOptions{Strategy: strategy})
+		if len(plan.Items) != 1 || plan.Items[0].Action != actionSkipExisting || plan.Items[0].Conflict {
+			t.Errorf("策略 %v: 计划 %+v，期望跳过已存在", strategy, plan.Items)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 网络设置：所有对外请求（GraphQL、令牌刷新）共用一个 http.Transport，复用连接。
+// 代理和根证书可通过设置文件或环境变量配置（环境变量优先），用于公司内网的出口代理和自签 CA。
+// 未指定代理时沿用系统的 HTTPS_PROXY / HTTP_PROXY / NO_PROXY。
+
+const (
+	envProxy         = "WARPMINI_PROXY"           // 代理地址，支持 http、https、socks5、socks5h；direct 表示不使用代理
+	envCAFile        = "WARPMINI_CA_FILE"         // 追加信任的根证书（PEM，可含多个）
+	envTLSMinVersion = "WARPMINI_TLS_MIN_VERSION" // 最低 TLS 版本：1.2 或 1.3
+
+	proxyDirect = "direct"
+)
+
+// errNetworkConfig 网络设置有误，重试无意义
+var errNetworkConfig = errors.New("网络设置有误")
+
+// networkConfig 实际使用的网络设置
+type networkConfig struct {
+	Proxy         string
+	CAFile        string
+	TLSMinVersion string
+}
+
+// resolveNetworkConfig 按 环境变量 > 设置文件 的顺序确定网络设置
+func resolveNetworkConfig(s appSettings, getenv func(string) string) networkConfig {
+	pick := func(env, setting string) string {
+		if v := strings.TrimSpace(getenv(env)); v != "" {
+			return v
+		}
+		return strings.TrimSpace(setting)
+	}
+	return networkConfig{
+		Proxy:         pick(envProxy, s.ProxyURL),
+		CAFile:        pick(envCAFile, s.CAFile),
+		TLSMinVersion: pick(envTLSMinVersion, s.TLSMinVersion),
+	}
+}
+
+// newTransport 按设置构造 Transport，其余参数（连接池、超时、HTTP/2）与 http.DefaultTransport 相同
+func newTransport(cfg networkConfig) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch strings.ToLower(cfg.Proxy) {
+	case "":
+		t.Proxy = http.ProxyFromEnvironment
+	case proxyDirect:
+		t.Proxy = nil
+	default:
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("代理地址无效: %q", cfg.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("代理地址 %q 不受支持，仅支持 http、https、socks5、socks5h", cfg.Proxy)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch cfg.TLSMinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsCfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("最低 TLS 版本无效: %q，可选 1.2 或 1.3", cfg.TLSMinVersion)
+	}
+	if cfg.CAFile != "" {
+		path, err := expandHome(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取根证书失败: %w", err)
+		}
+		// 在系统根证书的基础上追加，公网地址仍可正常校验
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("根证书文件 %s 中没有可用的 PEM 证书", path)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	t.TLSClientConfig = tlsCfg
+	return t, nil
+}
+
+var (
+	transportOnce sync.Once
+	transport     *http.Transport
+	transportErr  error
+)
+
+// sharedTransport 返回共用的 Transport（首次使用时按设置构造）
+func sharedTransport() (*http.Transport, error) {
+	transportOnce.Do(func() {
+		s, err := loadSettings()
+		if err != nil {
+			transportErr = err
+			return
+		}
+		transport, transportErr = newTransport(resolveNetworkConfig(s, os.Getenv))
+	})
+	return transport, transportErr
+}
+
+// lazyTransport 把请求交给 sharedTransport；设置有误时每个请求都返回该错误
+type lazyTransport struct{}
+
+func (lazyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t, err := sharedTransport()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %w", errNetworkConfig, err)
+	}
+	return t.RoundTrip(req)
+}
+
+// newHTTPClient 返回使用共用 Transport 的客户端
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: lazyTransport{}, Timeout: timeout}
+}
+
+var (
+	// gqlHTTPClient 所有 GraphQL 请求共用
+	gqlHTTPClient = newHTTPClient(30 * time.Second)
+	// tokenHTTPClient 令牌刷新使用
+	tokenHTTPClient = newHTTPClient(12 * time.Second)
+)
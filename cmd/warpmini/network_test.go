@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveNetworkConfig(t *testing.T) {
+	file := appSettings{ProxyURL: "http://file-proxy:3128", CAFile: "~/ca.pem", TLSMinVersion: "1.2"}
+	got := resolveNetworkConfig(file, envMap(map[string]string{envProxy: "socks5://env-proxy:1080", envTLSMinVersion: " 1.3 "}))
+	want := networkConfig{Proxy: "socks5://env-proxy:1080", CAFile: "~/ca.pem", TLSMinVersion: "1.3"}
+	if got != want {
+		t.Errorf("resolveNetworkConfig = %+v，期望 %+v", got, want)
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://app.warp.dev/graphql/v2", nil)
+	tests := []struct {
+		proxy string
+		want  string // 期望的代理地址，空表示直连
+	}{
+		{"http://proxy.corp:3128", "http://proxy.corp:3128"},
+		{"socks5h://127.0.0.1:1080", "socks5h://127.0.0.1:1080"},
+		{"direct", ""},
+		{"DIRECT", ""},
+	}
+	for _, tt := range tests {
+		tr, err := newTransport(networkConfig{Proxy: tt.proxy})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.proxy, err)
+		}
+		got := ""
+		if tr.Proxy != nil {
+			u, err := tr.Proxy(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u != nil {
+				got = u.String()
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: 代理 = %q，期望 %q", tt.proxy, got, tt.want)
+		}
+	}
+}
+
+func TestNewTransportErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not.pem")
+	os.WriteFile(notPEM, []byte("hello"), 0o600)
+	tests := []struct {
+		name string
+		cfg  networkConfig
+		want string
+	}{
+		{"代理缺少主机", networkConfig{Proxy: "proxy.corp:3128"}, "代理地址"},
+		{"代理协议不支持", networkConfig{Proxy: "ftp://proxy.corp"}, "不受支持"},
+		{"TLS 版本无效", networkConfig{TLSMinVersion: "1.1"}, "最低 TLS 版本无效"},
+		{"根证书不存在", networkConfig{CAFile: filepath.Join(dir, "missing.pem")}, "读取根证书失败"},
+		{"根证书不是 PEM", networkConfig{CAFile: notPEM}, "没有可用的 PEM 证书"},
+	}
+	for _, tt := range tests {
+		if _, err := newTransport(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 = %v，期望包含 %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewTransportCustomCA(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // 第一次请求的握手失败是预期的
+	srv.StartTLS()
+	defer srv.Close()
+
+	// 未追加测试服务器的证书时校验失败
+	tr, err := newTransport(networkConfig{Proxy: proxyDirect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: tr}).Get(srv.URL); err == nil {
+		t.Fatal("未信任的证书应校验失败")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tr, err = newTransport(networkConfig{Proxy: proxyDirect, CAFile: caFile, TLSMinVersion: "1.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x，期望 TLS 1.3", tr.TLSClientConfig.MinVersion)
+	}
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("追加根证书后应校验通过: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 长时间操作（登录、备份、恢复）的取消：每个操作开始时从 operations 取得 context，
+// 界面上的“取消”按钮取消所有进行中的操作。自动备份不受该按钮影响。
+
+// operations 进行中的可取消操作
+type operations struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+
+	// OnChange 在有无进行中的操作发生变化时调用（用于显示或隐藏取消按钮）
+	OnChange func(running bool)
+}
+
+// Start 开始一个操作；操作结束时必须调用 done
+func (o *operations) Start() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.mu.Lock()
+	if o.cancels == nil {
+		o.cancels = map[int]context.CancelFunc{}
+	}
+	id := o.next
+	o.next++
+	o.cancels[id] = cancel
+	first := len(o.cancels) == 1
+	o.mu.Unlock()
+	if first && o.OnChange != nil {
+		o.OnChange(true)
+	}
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			o.mu.Lock()
+			delete(o.cancels, id)
+			last := len(o.cancels) == 0
+			o.mu.Unlock()
+			if last && o.OnChange != nil {
+				o.OnChange(false)
+			}
+		})
+	}
+}
+
+// CancelAll 取消所有进行中的操作
+func (o *operations) CancelAll() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, cancel := range o.cancels {
+		cancel()
+	}
+}
+
+// isCancelled 错误是否由用户取消引起
+func isCancelled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,862 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 恢复计划：先把备份与云端现状对比，得出每个条目的处理方式（创建/已存在跳过/冲突/无效），
+// 预览阶段不发送任何 mutation；确认后再按计划执行。
+
+type planAction string
+
+const (
+	actionCreate       planAction = "create"
+	actionUpdate       planAction = "update"        // 冲突时覆盖云端同名对象
+	actionSkipExisting planAction = "skip-existing" // 云端已有相同内容
+	actionSkipConflict planAction = "skip-conflict" // 同名但内容不同，按策略跳过
+	actionInvalid      planAction = "invalid"
+)
+
+// conflictStrategy 同名但内容不同（按内容哈希判断）时的处理方式
+type conflictStrategy string
+
+const (
+	conflictSkip      conflictStrategy = "skip"
+	conflictOverwrite conflictStrategy = "overwrite"
+	conflictRename    conflictStrategy = "rename"
+	conflictKeepBoth  conflictStrategy = "keep-both"
+)
+
+// conflictStrategies 按界面展示顺序排列
+var conflictStrategies = []conflictStrategy{conflictSkip, conflictOverwrite, conflictRename, conflictKeepBoth}
+
+// Label 用于界面展示
+func (s conflictStrategy) Label() string {
+	switch s {
+	case conflictOverwrite:
+		return "覆盖已有"
+	case conflictRename:
+		return "重命名副本"
+	case conflictKeepBoth:
+		return "都保留"
+	default:
+		return "跳过"
+	}
+}
+
+// 可恢复条目的种类；genericStringObject 另以 Format 区分具体格式
+const (
+	kindFolder   = "folder"
+	kindObject   = "object"
+	kindWorkflow = "workflow"
+	kindNotebook = "notebook"
+)
+
+// restoreItem 备份中的一个可恢复条目
+type restoreItem struct {
+	ID       string // 条目在备份中的稳定标识，如 "object:3"，用于选择性恢复
+	Kind     string
+	Format   string // 仅 genericStringObject
+	Name     string
+	Data     string // serializedModel / 工作流 data / 笔记本 data
+	FolderID string // 备份中所属文件夹的 uid
+	UID      string // 仅文件夹：备份中的 uid
+	Path     string // 仅文件夹：完整路径
+}
+
+// Label 用于界面展示，如 "MCP · github"
+func (it restoreItem) Label() string {
+	var kind string
+	switch it.Kind {
+	case kindFolder:
+		kind = "文件夹"
+	case kindWorkflow:
+		kind = "工作流"
+	case kindNotebook:
+		kind = "笔记本"
+	default:
+		kind = formatLabel(it.Format)
+	}
+	name := it.Name
+	if it.Kind == kindFolder && it.Path != "" {
+		name = it.Path
+	}
+	if name == "" {
+		name = "（无名称）"
+	}
+	return kind + " · " + name
+}
+
+// dedupeKey 返回与云端已有对象比较用的去重键，空表示不去重
+func (it restoreItem) dedupeKey() string {
+	switch it.Kind {
+	case kindFolder:
+		return "folder:" + it.Path
+	case kindWorkflow:
+		if it.Name != "" {
+			return "workflow:" + it.Name
+		}
+		return ""
+	case kindNotebook:
+		if it.Name != "" {
+			return "notebook:" + it.Name
+		}
+		return ""
+	default:
+		return objectDedupeKey(it.Format, it.Data)
+	}
+}
+
+// contentKey 种类/格式 + 内容哈希，用于识别云端已有的相同内容；文件夹没有内容，返回空
+func (it restoreItem) contentKey() string {
+	if it.Kind == kindFolder || it.Data == "" {
+		return ""
+	}
+	return it.Kind + ":" + it.Format + ":" + objectContentHash(it.Format, it.Data)
+}
+
+// renamed 返回改名后的条目副本
+func (it restoreItem) renamed(name string) (restoreItem, error) {
+	switch it.Kind {
+	case kindObject:
+		data, err := renameObject(it.Format, it.Data, name)
+		if err != nil {
+			return it, err
+		}
+		it.Data = data
+	case kindWorkflow:
+		var wf map[string]any
+		if err := json.Unmarshal([]byte(it.Data), &wf); err != nil {
+			return it, err
+		}
+		wf["name"] = name
+		b, err := json.Marshal(wf)
+		if err != nil {
+			return it, err
+		}
+		it.Data = string(b)
+	case kindNotebook:
+		// 笔记本标题单独存放，内容不变
+	default:
+		return it, fmt.Errorf("%s 不支持重命名", it.Kind)
+	}
+	it.Name = name
+	return it, nil
+}
+
+// backupItems 把备份展开为按恢复顺序排列的条目：文件夹（父在前）、genericStringObject、工作流、笔记本
+func backupItems(bd BackupData) []restoreItem {
+	var items []restoreItem
+	paths := folderPaths(bd.Folders)
+	for i, f := range sortFoldersByDepth(bd.Folders) {
+		uid := asString(f["uid"])
+		items = append(items, restoreItem{
+			ID:       fmt.Sprintf("%s:%d", kindFolder, i),
+			Kind:     kindFolder,
+			Name:     asString(f["name"]),
+			FolderID: asString(f["folderId"]),
+			UID:      uid,
+			Path:     paths[uid],
+		})
+	}
+	for i, m := range bd.genericObjects() {
+		format := asString(m["format"])
+		serialized := asString(m["serializedModel"])
+		items = append(items, restoreItem{
+			ID:       fmt.Sprintf("%s:%d", kindObject, i),
+			Kind:     kindObject,
+			Format:   format,
+			Name:     objectName(format, serialized),
+			Data:     serialized,
+			FolderID: asString(m["folderId"]),
+		})
+	}
+	for i, m := range bd.Workflows {
+		data := workflowData(m["data"])
+		items = append(items, restoreItem{
+			ID:       fmt.Sprintf("%s:%d", kindWorkflow, i),
+			Kind:     kindWorkflow,
+			Name:     workflowName(data),
+			Data:     data,
+			FolderID: asString(m["folderId"]),
+		})
+	}
+	for i, m := range bd.Notebooks {
+		items = append(items, restoreItem{
+			ID:       fmt.Sprintf("%s:%d", kindNotebook, i),
+			Kind:     kindNotebook,
+			Name:     asString(m["title"]),
+			Data:     asString(m["data"]),
+			FolderID: asString(m["folderId"]),
+		})
+	}
+	return items
+}
+
+// cloudObject 云端已有的一个对象
+type cloudObject struct {
+	restoreItem
+	UID string
+}
+
+// cloudObjects 把 GetUpdatedCloudObjects 的结果展开为与备份条目同构的对象列表
+func cloudObjects(cloud updatedCloudObjects) []cloudObject {
+	var objs []cloudObject
+	folders := cloudFolders(cloud)
+	paths := folderPaths(folders)
+	for _, f := range folders {
+		uid := asString(f["uid"])
+		objs = append(objs, cloudObject{UID: uid, restoreItem: restoreItem{
+			Kind: kindFolder, Name: asString(f["name"]), FolderID: asString(f["folderId"]), UID: uid, Path: paths[uid],
+		}})
+	}
+	for _, o := range cloud.GenericStringObjects {
+		objs = append(objs, cloudObject{UID: o.Metadata.UID, restoreItem: restoreItem{
+			Kind: kindObject, Format: o.Format, Name: objectName(o.Format, o.SerializedModel), Data: o.SerializedModel, FolderID: o.Metadata.FolderID,
+		}})
+	}
+	for _, w := range cloud.Workflows {
+		data := string(w.Data)
+		objs = append(objs, cloudObject{UID: w.Metadata.UID, restoreItem: restoreItem{
+			Kind: kindWorkflow, Name: workflowName(data), Data: data, FolderID: w.Metadata.FolderID,
+		}})
+	}
+	for _, n := range cloud.Notebooks {
+		objs = append(objs, cloudObject{UID: n.Metadata.UID, restoreItem: restoreItem{
+			Kind: kindNotebook, Name: n.Title, Data: n.Data, FolderID: n.Metadata.FolderID,
+		}})
+	}
+	return objs
+}
+
+// cloudInventory 当前账号在云端已有的对象，用于去重和冲突检测
+type cloudInventory struct {
+	Keys    map[string]cloudObject // 去重键（名称）-> 云端对象
+	Hashes  map[string]bool        // 内容键，见 restoreItem.contentKey
+	Folders map[string]string      // 文件夹路径 -> uid
+	Objects []cloudObject          // 全部云端对象，镜像模式用来找出备份中没有的对象
+	Skipped []string               // 缺少必填字段而被忽略的云端对象路径
+}
+
+// buildCloudInventory 从 GetUpdatedCloudObjects 的结果建立去重索引
+func buildCloudInventory(cloud updatedCloudObjects) cloudInventory {
+	inv := cloudInventory{Keys: map[string]cloudObject{}, Hashes: map[string]bool{}, Folders: map[string]string{}}
+	inv.Objects = cloudObjects(cloud)
+	inv.Skipped = cloud.Skipped
+	for _, obj := range inv.Objects {
+		if obj.Kind == kindFolder {
+			inv.Folders[obj.Path] = obj.UID
+		}
+		if key := obj.dedupeKey(); key != "" {
+			if _, dup := inv.Keys[key]; !dup {
+				inv.Keys[key] = obj
+			}
+		}
+		if ck := obj.contentKey(); ck != "" {
+			inv.Hashes[ck] = true
+		}
+	}
+	return inv
+}
+
+// planItem 计划中的一个条目
+type planItem struct {
+	restoreItem
+	Action       planAction
+	Reason       string // 无效原因
+	ExistingUID  string // 云端已存在的同名对象（或文件夹）的 uid
+	Conflict     bool   // 与云端同名对象内容不同
+	OriginalName string // 重命名副本前的名称
+
+	MissingSecrets []string // 未能解析的密钥占位符（此时 Action 为 actionInvalid）
+}
+
+// restorePlan 恢复计划
+type restorePlan struct {
+	Items          []planItem
+	Strategy       conflictStrategy
+	MissingSecrets []string          // 未能解析的密钥占位符，相关条目不会上传
+	ExistingFolder map[string]string // 备份文件夹 uid -> 云端同路径文件夹 uid（含未选中的文件夹）
+	BackupUID      string            // 备份所属账号
+	BackupEmail    string
+	Mirror         bool          // 镜像模式
+	Removals       []cloudObject // 镜像模式下要移到回收站的云端对象
+	CloudSkipped   []string      // 云端响应中缺少必填字段而未参与对比的对象路径
+}
+
+// mirrorFormats 镜像模式管理的对象格式：只有这些格式的云端对象会被移除
+var mirrorFormats = map[string]bool{formatMCPServer: true, formatAIFact: true}
+
+// mirrorScope 返回镜像模式实际管理的格式。完整备份管理全部 mirrorFormats；
+// 从 mcp.json 或 Markdown 导入的备份只含一部分，只管理其中有条目的格式，
+// 避免导入 mcp.json 后镜像恢复把云端的规则全部移除
+func mirrorScope(bd BackupData) map[string]bool {
+	scope := map[string]bool{}
+	for _, m := range bd.genericObjects() {
+		if f := asString(m["format"]); mirrorFormats[f] {
+			scope[f] = true
+		}
+	}
+	if bd.BackupType == "global" {
+		for f := range mirrorFormats {
+			scope[f] = true
+		}
+	}
+	return scope
+}
+
+// mirrorRemovals 找出 scope 内云端有、备份中没有的对象。与整份备份比较，不受选择性恢复影响。
+// 保留的云端对象：与备份条目内容相同的（每个条目认领一个），以及会被同名条目覆盖的
+// （planRestore 覆盖的是 inv.Keys 中的那一个）；其余同名的旧副本也会移除，恢复后与备份一致
+func mirrorRemovals(items []restoreItem, inv cloudInventory, scope map[string]bool) []cloudObject {
+	byContent := map[string][]string{}
+	for _, obj := range inv.Objects {
+		if obj.Kind == kindObject && scope[obj.Format] && obj.UID != "" {
+			k := obj.contentKey()
+			byContent[k] = append(byContent[k], obj.UID)
+		}
+	}
+	keep := map[string]bool{}
+	for _, it := range items {
+		if it.Kind != kindObject || !scope[it.Format] {
+			continue
+		}
+		if uids := byContent[it.contentKey()]; len(uids) > 0 {
+			keep[uids[0]] = true
+			byContent[it.contentKey()] = uids[1:]
+			continue
+		}
+		if existing, ok := inv.Keys[it.dedupeKey()]; ok && it.dedupeKey() != "" {
+			keep[existing.UID] = true
+		}
+	}
+	var removals []cloudObject
+	for _, obj := range inv.Objects {
+		if obj.Kind != kindObject || !scope[obj.Format] || obj.UID == "" || keep[obj.UID] {
+			continue
+		}
+		removals = append(removals, obj)
+	}
+	return removals
+}
+
+// accountMismatch 备份来自其他账号时返回提示，否则为空；
+// 旧备份没有记录 uid 时按邮箱比较，任一方未知时不提示
+func (p restorePlan) accountMismatch(userID, email string) string {
+	switch {
+	case p.BackupUID != "" && userID != "":
+		if p.BackupUID == userID {
+			return ""
+		}
+	case p.BackupEmail != "" && email != "":
+		if strings.EqualFold(p.BackupEmail, email) {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return fmt.Sprintf("该备份来自账号 %s，当前登录的是 %s。恢复后这些配置会写入当前账号，确定继续吗？",
+		accountDisplay(p.BackupEmail, p.BackupUID), accountDisplay(email, userID))
+}
+
+// accountDisplay 账号的展示形式，如 "a@b.com（uid）"
+func accountDisplay(email, uid string) string {
+	switch {
+	case email != "" && uid != "":
+		return email + "（" + uid + "）"
+	case email != "":
+		return email
+	default:
+		return uid
+	}
+}
+
+// selectedItems 按 opts.Selected 过滤条目，Selected 为 nil 表示全部
+func selectedItems(items []restoreItem, opts restoreOptions) []restoreItem {
+	if opts.Selected == nil {
+		return items
+	}
+	var out []restoreItem
+	for _, it := range items {
+		if opts.Selected[it.ID] {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// planRestore 对比备份与云端现状生成计划，纯计算，不访问网络。
+// opts.Secrets 为已查找到的密钥占位符值。
+func planRestore(bd BackupData, inv cloudInventory, opts restoreOptions) restorePlan {
+	strategy := opts.Strategy
+	plan := restorePlan{Strategy: strategy, ExistingFolder: map[string]string{}, BackupUID: bd.AccountUID, BackupEmail: bd.AccountEmail, CloudSkipped: inv.Skipped}
+	missingSeen := map[string]bool{}
+	all := backupItems(bd)
+	// 未选中的文件夹不会创建，但云端已有同路径文件夹时，选中的对象仍放回其中
+	for _, it := range all {
+		if it.Kind == kindFolder {
+			if uid, ok := inv.Folders[it.Path]; ok {
+				plan.ExistingFolder[it.UID] = uid
+			}
+		}
+	}
+	// 重命名时避免与云端及本次要恢复的条目重名；备份中条目的原名优先，副本另取编号
+	selected := selectedItems(all, opts)
+	usedKeys := map[string]bool{}
+	for k := range inv.Keys {
+		usedKeys[k] = true
+	}
+	for _, it := range selected {
+		if k := it.dedupeKey(); k != "" {
+			usedKeys[k] = true
+		}
+	}
+	// 镜像模式下同名冲突一律覆盖，否则跳过或另建副本后云端仍与备份不一致
+	var scope map[string]bool
+	if opts.Mirror {
+		scope = mirrorScope(bd)
+	}
+	for _, it := range selected {
+		// 先填回密钥占位符，冲突检测基于真实内容
+		var missing []string
+		it.Data, missing = fillPlaceholders(it.Data, opts.Secrets)
+		for _, name := range missing {
+			if !missingSeen[name] {
+				missingSeen[name] = true
+				plan.MissingSecrets = append(plan.MissingSecrets, name)
+			}
+		}
+		p := planItem{restoreItem: it, Action: actionCreate}
+		key := it.dedupeKey()
+		existing, nameTaken := inv.Keys[key]
+		switch {
+		case invalidReason(it) != "":
+			p.Action = actionInvalid
+			p.Reason = invalidReason(it)
+		case len(missing) > 0:
+			p.Action = actionInvalid
+			p.Reason = "未解析的密钥占位符: " + strings.Join(missing, ", ")
+			p.MissingSecrets = missing
+		case it.Kind == kindFolder && nameTaken:
+			p.Action = actionSkipExisting
+			p.ExistingUID = inv.Folders[it.Path]
+		case inv.Hashes[it.contentKey()]:
+			// 云端已有完全相同的内容（不论名称）
+			p.Action = actionSkipExisting
+		case key != "" && nameTaken:
+			p.Conflict = true
+			p.ExistingUID = existing.UID
+			itemStrategy := strategy
+			if scope[it.Format] {
+				itemStrategy = conflictOverwrite
+			}
+			switch itemStrategy {
+			case conflictOverwrite:
+				p.Action = actionUpdate
+				if existing.UID == "" {
+					p.Action = actionInvalid
+					p.Reason = "云端同名对象缺少 uid，无法覆盖"
+				}
+			case conflictRename:
+				name := renamedName(it, usedKeys)
+				renamed, err := it.renamed(name)
+				if err != nil {
+					p.Action = actionInvalid
+					p.Reason = "无法重命名: " + err.Error()
+					break
+				}
+				p.OriginalName = it.Name
+				p.restoreItem = renamed
+			case conflictKeepBoth:
+				p.Action = actionCreate
+			default:
+				p.Action = actionSkipConflict
+			}
+		}
+		if k := p.dedupeKey(); k != "" && p.Action == actionCreate {
+			usedKeys[k] = true
+		}
+		plan.Items = append(plan.Items, p)
+	}
+	if opts.Mirror {
+		plan.Mirror = true
+		// 与云端比较的是真实内容，脱敏备份同样先填回占位符
+		filled := make([]restoreItem, len(all))
+		for i, it := range all {
+			it.Data, _ = fillPlaceholders(it.Data, opts.Secrets)
+			filled[i] = it
+		}
+		plan.Removals = mirrorRemovals(filled, inv, scope)
+	}
+	return plan
+}
+
+// renamedName 为冲突条目生成未被占用的新名称，如 "name (恢复)"、"name (恢复 2)"
+func renamedName(it restoreItem, used map[string]bool) string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s (恢复)", it.Name)
+		if i > 1 {
+			name = fmt.Sprintf("%s (恢复 %d)", it.Name, i)
+		}
+		probe := it
+		probe.Name = name
+		if it.Kind == kindObject {
+			if data, err := renameObject(it.Format, it.Data, name); err == nil {
+				probe.Data = data
+			}
+		}
+		if !used[probe.dedupeKey()] {
+			return name
+		}
+	}
+}
+
+// invalidReason 检查条目是否可以恢复，返回空表示有效
+func invalidReason(it restoreItem) string {
+	switch it.Kind {
+	case kindFolder:
+		if it.UID == "" || strings.TrimSpace(it.Name) == "" {
+			return "缺少文件夹 uid 或名称"
+		}
+	case kindObject:
+		if it.Format == "" {
+			return "缺少 format"
+		}
+		if it.Data == "" {
+			return "serializedModel 为空"
+		}
+	case kindWorkflow:
+		if it.Data == "" {
+			return "工作流内容为空"
+		}
+	}
+	return ""
+}
+
+// planCounts 计划中各类动作的数量
+type planCounts struct {
+	Create, Update, Skip, Invalid, Conflicts int
+}
+
+// Counts 统计计划中各类动作的数量
+func (p restorePlan) Counts() planCounts {
+	var c planCounts
+	for _, it := range p.Items {
+		switch it.Action {
+		case actionCreate:
+			c.Create++
+		case actionUpdate:
+			c.Update++
+		case actionSkipExisting, actionSkipConflict:
+			c.Skip++
+		case actionInvalid:
+			c.Invalid++
+		}
+		if it.Conflict {
+			c.Conflicts++
+		}
+	}
+	return c
+}
+
+// Summary 计划摘要
+func (p restorePlan) Summary() string {
+	c := p.Counts()
+	summary := fmt.Sprintf("将创建 %d，覆盖 %d，跳过 %d，无效 %d；冲突 %d（%s）",
+		c.Create, c.Update, c.Skip, c.Invalid, c.Conflicts, p.Strategy.Label())
+	if p.Mirror {
+		summary += fmt.Sprintf("；镜像移除 %d（MCP 和规则的同名冲突一律覆盖）", len(p.Removals))
+	}
+	return summary + skippedObjectsNote(p.CloudSkipped)
+}
+
+// RemovalLines 镜像模式下要移除的对象，每个一行
+func (p restorePlan) RemovalLines() []string {
+	lines := make([]string, len(p.Removals))
+	for i, obj := range p.Removals {
+		lines[i] = obj.Label()
+	}
+	return lines
+}
+
+// Lines 计划明细，每个条目一行
+func (p restorePlan) Lines() []string {
+	lines := make([]string, 0, len(p.Items))
+	for _, it := range p.Items {
+		var tag string
+		switch it.Action {
+		case actionCreate:
+			tag = "[创建]"
+			if it.Conflict && it.OriginalName != "" {
+				tag = "[冲突·重命名]"
+			} else if it.Conflict {
+				tag = "[冲突·都保留]"
+			}
+		case actionUpdate:
+			tag = "[冲突·覆盖]"
+		case actionSkipExisting:
+			tag = "[已存在]"
+		case actionSkipConflict:
+			tag = "[冲突·跳过]"
+		case actionInvalid:
+			tag = "[无效]"
+		}
+		line := tag + " " + it.Label()
+		if it.OriginalName != "" {
+			line += "（原名 " + it.OriginalName + "）"
+		}
+		if it.Reason != "" {
+			line += "：" + it.Reason
+		}
+		lines = append(lines, line)
+	}
+	for _, line := range p.RemovalLines() {
+		lines = append(lines, "[移除] "+line)
+	}
+	return lines
+}
+
+// restoreLimits 恢复时的并发数和每秒请求数上限
+type restoreLimits struct {
+	Concurrency   int
+	RatePerSecond int
+}
+
+// rateLimiter 简单的匀速限流器，nil 表示不限速
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	// 设置值超过每秒 1e9 次时间隔会算成 0，NewTicker 不接受
+	interval := max(time.Second/time.Duration(perSecond), time.Nanosecond)
+	return &rateLimiter{ticker: time.NewTicker(interval)}
+}
+
+// Wait 阻塞到允许发出下一个请求；ctx 结束时返回其错误
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) Stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
+
+// itemResult 单个条目的执行结果
+type itemResult int
+
+const (
+	itemNone itemResult = iota // 无效条目，不计入统计
+	itemSuccess
+	itemSkipped
+	itemFailed
+	itemCancelled // 取消时尚未执行或执行中被中断
+)
+
+// executeRestorePlan 按计划创建或覆盖对象；无效条目不计入统计（与以往一致）。
+// 文件夹按层级顺序逐个创建（子文件夹依赖父文件夹的新 uid），其余条目由工作池并发执行；
+// 结果按计划顺序汇总，统计与顺序执行时一致。ctx 取消后不再开始新条目，结果中给出已完成的部分。
+func executeRestorePlan(ctx context.Context, client *gqlClient, plan restorePlan, userID string, limits restoreLimits) RestoreResult {
+	// 备份中的文件夹 uid -> 当前账号中的 uid；文件夹阶段结束后只读
+	folderMap := map[string]string{}
+	for k, v := range plan.ExistingFolder {
+		folderMap[k] = v
+	}
+	limiter := newRateLimiter(limits.RatePerSecond)
+	defer limiter.Stop()
+	// 未执行到的条目保持 itemCancelled
+	results := make([]itemResult, len(plan.Items))
+	outcomes := make([]string, len(plan.Items))
+	for i := range results {
+		results[i], outcomes[i] = itemCancelled, "未执行（已取消）"
+	}
+	var others []int
+	for i, it := range plan.Items {
+		if it.Kind != kindFolder {
+			others = append(others, i)
+			continue
+		}
+		if ctx.Err() != nil {
+			continue
+		}
+		results[i], outcomes[i] = executePlanItem(ctx, client, it, userID, folderMap, limiter)
+	}
+	workers := limits.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], outcomes[i] = executePlanItem(ctx, client, plan.Items[i], userID, folderMap, limiter)
+			}
+		}()
+	}
+dispatch:
+	for _, i := range others {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := RestoreResult{}
+	// 镜像模式：在创建和覆盖之后把备份中没有的对象移到回收站
+	for _, obj := range plan.Removals {
+		if limiter.Wait(ctx) != nil {
+			res.TotalCancelled++
+			continue
+		}
+		if err := client.TrashObject(ctx, obj.UID); err != nil {
+			if ctx.Err() != nil {
+				res.TotalCancelled++
+				continue
+			}
+			res.TotalFailed++
+			res.RemoveFailures = append(res.RemoveFailures, obj.Label()+": "+err.Error())
+			continue
+		}
+		res.TotalRemoved++
+	}
+	for i, it := range plan.Items {
+		switch results[i] {
+		case itemSuccess:
+			res.TotalSuccess++
+		case itemSkipped:
+			res.TotalSkipped++
+		case itemFailed:
+			res.TotalFailed++
+		case itemCancelled:
+			res.TotalCancelled++
+		}
+		if len(it.MissingSecrets) > 0 {
+			res.UnresolvedSecrets = append(res.UnresolvedSecrets, it.Label()+": "+strings.Join(it.MissingSecrets, ", "))
+		}
+		if it.Conflict {
+			name := it.Name
+			if it.OriginalName != "" {
+				name = it.OriginalName
+			}
+			res.Conflicts = append(res.Conflicts, restoreConflict{
+				Item:     it.Label(),
+				Name:     name,
+				Strategy: string(plan.Strategy),
+				Outcome:  outcomes[i],
+			})
+		}
+	}
+	res.Cancelled = ctx.Err() != nil
+	res.Success = res.TotalFailed == 0 && !res.Cancelled
+	if res.Cancelled {
+		res.Message = fmt.Sprintf("已取消恢复: 成功 %d，跳过 %d，失败 %d，未完成 %d", res.TotalSuccess, res.TotalSkipped, res.TotalFailed, res.TotalCancelled)
+	} else if res.Success {
+		res.Message = fmt.Sprintf("恢复完成: 成功 %d，跳过 %d", res.TotalSuccess, res.TotalSkipped)
+	} else {
+		res.Message = fmt.Sprintf("部分成功: 成功 %d，跳过 %d，失败 %d", res.TotalSuccess, res.TotalSkipped, res.TotalFailed)
+	}
+	if plan.Mirror {
+		res.Message += fmt.Sprintf("，移除 %d", res.TotalRemoved)
+	}
+	if len(res.UnresolvedSecrets) > 0 {
+		res.Message += fmt.Sprintf("，%d 项因密钥未解析未上传", len(res.UnresolvedSecrets))
+	}
+	return res
+}
+
+// executePlanItem 执行单个计划条目，返回结果和展示用的说明
+func executePlanItem(ctx context.Context, client *gqlClient, it planItem, userID string, folderMap map[string]string, limiter *rateLimiter) (itemResult, string) {
+	switch it.Action {
+	case actionInvalid:
+		return itemNone, ""
+	case actionSkipExisting, actionSkipConflict:
+		return itemSkipped, "已跳过"
+	case actionUpdate:
+		if limiter.Wait(ctx) != nil {
+			return itemCancelled, "未执行（已取消）"
+		}
+		if err := updateRestoreItem(ctx, client, it.restoreItem, it.ExistingUID); err != nil {
+			if ctx.Err() != nil {
+				return itemCancelled, "已取消（覆盖可能未完成）"
+			}
+			return itemFailed, "覆盖失败: " + err.Error()
+		}
+		return itemSuccess, "已覆盖"
+	}
+	if limiter.Wait(ctx) != nil {
+		return itemCancelled, "未执行（已取消）"
+	}
+	ok, skipped, err := createRestoreItem(ctx, client, it.restoreItem, userID, folderMap)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return itemCancelled, "已取消（请求可能已被处理）"
+	case err != nil:
+		return itemFailed, "创建失败: " + err.Error()
+	case skipped:
+		return itemSkipped, "已跳过（唯一键冲突）"
+	case ok && it.OriginalName != "":
+		return itemSuccess, "已创建副本 " + it.Name
+	case ok:
+		return itemSuccess, "已创建"
+	}
+	return itemNone, ""
+}
+
+// updateRestoreItem 用备份内容覆盖云端同名对象
+func updateRestoreItem(ctx context.Context, client *gqlClient, it restoreItem, uid string) error {
+	switch it.Kind {
+	case kindWorkflow:
+		return client.UpdateWorkflow(ctx, uid, it.Data)
+	case kindNotebook:
+		return client.UpdateNotebook(ctx, uid, it.Name, it.Data)
+	case kindObject:
+		return client.UpdateGenericStringObject(ctx, uid, it.Data)
+	default:
+		return fmt.Errorf("%s 不支持覆盖", it.Kind)
+	}
+}
+
+// createRestoreItem 创建单个条目；父文件夹未能恢复时对象放到根目录。
+// 只有创建文件夹时写入 folderMap，文件夹总是在并发阶段之前顺序创建
+func createRestoreItem(ctx context.Context, client *gqlClient, it restoreItem, userID string, folderMap map[string]string) (ok bool, skipped bool, err error) {
+	folderID := folderMap[it.FolderID]
+	switch it.Kind {
+	case kindFolder:
+		uid, err := client.CreateFolder(ctx, it.Name, folderID, userID)
+		if err != nil {
+			return false, false, err
+		}
+		folderMap[it.UID] = uid
+		return true, false, nil
+	case kindWorkflow:
+		return client.CreateWorkflow(ctx, it.Data, userID, folderID)
+	case kindNotebook:
+		return client.CreateNotebook(ctx, it.Name, it.Data, userID, folderID)
+	default:
+		return client.CreateGenericStringObject(ctx, it.Format, it.Data, userID, folderID)
+	}
+}
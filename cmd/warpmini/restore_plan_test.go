@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewRateLimiterExtremeRates(t *testing.T) {
+	for _, perSecond := range []int{-1, 0, 1000, 2_000_000_000, math.MaxInt} {
+		l := newRateLimiter(perSecond)
+		if err := l.Wait(context.Background()); err != nil {
+			t.Errorf("perSecond=%d: Wait = %v", perSecond, err)
+		}
+		l.Stop()
+	}
+}
+
+// mcpItem 备份中的一个 MCP 配置
+func mcpItem(name, command string) map[string]any {
+	return map[string]any{"format": formatMCPServer, "serializedModel": fmt.Sprintf(`{"name":%q,"command":%q}`, name, command)}
+}
+
+// ruleItem 备份中的一条规则
+func ruleItem(name, content string) map[string]any {
+	return map[string]any{"format": formatAIFact, "serializedModel": fmt.Sprintf(`{"memory":{"name":%q,"content":%q}}`, name, content)}
+}
+
+// cloudEntry 把备份条目作为 uid 为 uid 的云端对象
+func cloudEntry(uid string, m map[string]any) cloudGenericStringObject {
+	return cloudGenericStringObject{Format: asString(m["format"]), SerializedModel: asString(m["serializedModel"]), Metadata: objectMetadata{UID: uid}}
+}
+
+func inventoryOf(objs ...cloudGenericStringObject) cloudInventory {
+	return buildCloudInventory(updatedCloudObjects{GenericStringObjects: objs})
+}
+
+// removalUIDs 镜像模式要移除的对象 uid，排序后便于比较
+func removalUIDs(plan restorePlan) []string {
+	var uids []string
+	for _, obj := range plan.Removals {
+		uids = append(uids, obj.UID)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// fakeGraphQL 模拟 Warp GraphQL：create、update、trash 一律成功，记录调用次数和被移除的 uid
+type fakeGraphQL struct {
+	mu      sync.Mutex
+	ops     map[string]int
+	trashed []string
+}
+
+// newFakeGraphQL 启动模拟服务并让 currentEndpoints 指向它
+func newFakeGraphQL(t *testing.T) (*fakeGraphQL, *gqlClient) {
+	t.Helper()
+	f := &fakeGraphQL{ops: map[string]int{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := r.URL.Query().Get("op")
+		var req struct {
+			Variables struct {
+				Input struct {
+					UID string `json:"uid"`
+				} `json:"input"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.ops[op]++
+		n := f.ops[op]
+		if op == "TrashObject" {
+			f.trashed = append(f.trashed, req.Variables.Input.UID)
+		}
+		f.mu.Unlock()
+		created := map[string]any{"metadata": map[string]any{"uid": fmt.Sprintf("%s-%d", op, n)}}
+		field := strings.ToLower(op[:1]) + op[1:]
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{field: map[string]any{
+			"__typename":          op + "Output",
+			"genericStringObject": created,
+			"workflow":            created,
+			"notebook":            created,
+			"folder":              created,
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+	useTempHome(t)
+	t.Setenv(envGraphQLURL, srv.URL)
+	endpointsOnce = sync.Once{}
+	t.Cleanup(func() { endpointsOnce = sync.Once{} })
+	return f, &gqlClient{IDToken: "test"}
+}
+
+func TestPlanRestoreMirror(t *testing.T) {
+	a1, a2, a3 := mcpItem("a", "one"), mcpItem("a", "two"), mcpItem("a", "three")
+	rule, other := ruleItem("r", "x"), mcpItem("b", "b")
+	tests := []struct {
+		name     string
+		bd       BackupData
+		cloud    []cloudGenericStringObject
+		action   planAction
+		existing string   // 同名冲突时覆盖的对象
+		removals []string // 期望移除的对象 uid
+	}{
+		{
+			"同名不同内容强制覆盖",
+			BackupData{BackupType: "global", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a2)},
+			actionUpdate, "u1", nil,
+		},
+		{
+			"同名旧副本移除",
+			BackupData{BackupType: "global", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a2), cloudEntry("u2", a3)},
+			actionUpdate, "u1", []string{"u2"},
+		},
+		{
+			"内容相同的副本只保留一个",
+			BackupData{BackupType: "global", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a1), cloudEntry("u2", a1)},
+			actionSkipExisting, "", []string{"u2"},
+		},
+		{
+			"内容相同的优先于同名",
+			BackupData{BackupType: "global", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a2), cloudEntry("u2", a1)},
+			actionSkipExisting, "", []string{"u1"},
+		},
+		{
+			"完整备份移除备份中没有的规则",
+			BackupData{BackupType: "global", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a1), cloudEntry("u2", rule), cloudEntry("u3", other)},
+			actionSkipExisting, "", []string{"u2", "u3"},
+		},
+		{
+			"mcp.json 导入不移除规则",
+			BackupData{BackupType: "mcp_json", MCPServers: []map[string]any{a1}},
+			[]cloudGenericStringObject{cloudEntry("u1", a1), cloudEntry("u2", rule), cloudEntry("u3", other)},
+			actionSkipExisting, "", []string{"u3"},
+		},
+		{
+			"Markdown 导入不移除 MCP",
+			BackupData{BackupType: "rules_markdown", Rules: []map[string]any{ruleItem("s", "y")}},
+			[]cloudGenericStringObject{cloudEntry("u1", a1), cloudEntry("u2", rule)},
+			actionCreate, "", []string{"u2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planRestore(tt.bd, inventoryOf(tt.cloud...), restoreOptions{Strategy: conflictSkip, Mirror: true})
+			if len(plan.Items) != 1 {
+				t.Fatalf("计划 %d 个条目，期望 1", len(plan.Items))
+			}
+			if it := plan.Items[0]; it.Action != tt.action || (tt.existing != "" && it.ExistingUID != tt.existing) {
+				t.Errorf("动作 %s（%s），期望 %s（%s）", it.Action, it.ExistingUID, tt.action, tt.existing)
+			}
+			if got := removalUIDs(plan); !reflect.DeepEqual(got, tt.removals) {
+				t.Errorf("移除 %v，期望 %v", got, tt.removals)
+			}
+		})
+	}
+}
+
+// 未开启镜像时按所选策略处理冲突，不移除任何对象
+func TestPlanRestoreWithoutMirror(t *testing.T) {
+	bd := BackupData{BackupType: "global", MCPServers: []map[string]any{mcpItem("a", "one")}}
+	plan := planRestore(bd, inventoryOf(cloudEntry("u1", mcpItem("a", "two")), cloudEntry("u2", ruleItem("r", "x"))), restoreOptions{Strategy: conflictSkip})
+	if plan.Items[0].Action != actionSkipConflict || len(plan.Removals) != 0 {
+		t.Errorf("动作 %s、移除 %d，期望跳过冲突、不移除", plan.Items[0].Action, len(plan.Removals))
+	}
+}
+
+func TestExecuteRestorePlanMirror(t *testing.T) {
+	f, client := newFakeGraphQL(t)
+	bd := BackupData{BackupType: "global", MCPServers: []map[string]any{mcpItem("a", "one")}}
+	inv := inventoryOf(cloudEntry("u1", mcpItem("a", "two")), cloudEntry("u2", mcpItem("a", "three")), cloudEntry("u3", ruleItem("r", "x")))
+	plan := planRestore(bd, inv, restoreOptions{Strategy: conflictRename, Mirror: true})
+	res := executeRestorePlan(context.Background(), client, plan, "user", restoreLimits{Concurrency: 2})
+	if res.Error != "" || res.TotalSuccess != 1 || res.TotalRemoved != 2 || len(res.RemoveFailures) > 0 {
+		t.Fatalf("结果 %+v", res)
+	}
+	sort.Strings(f.trashed)
+	if want := []string{"u2", "u3"}; !reflect.DeepEqual(f.trashed, want) {
+		t.Errorf("移除 %v，期望 %v", f.trashed, want)
+	}
+	if f.ops["UpdateGenericStringObject"] != 1 || f.ops["CreateGenericStringObject"] != 0 {
+		t.Errorf("调用 %v，期望只覆盖一次、不创建副本", f.ops)
+	}
+}
+
+func TestPlanRestoreActions(t *testing.T) {
+	bd := BackupData{
+		Folders:    []map[string]any{{"uid": "f1", "name": "新文件夹"}, {"uid": "f2", "name": "已有"}},
+		MCPServers: []map[string]any{mcpItem("new", "x"), mcpItem("same", "x"), {"format": formatMCPServer, "serializedModel": ""}},
+		Workflows:  []map[string]any{{"data": ""}},
+	}
+	cloud := updatedCloudObjects{
+		GenericStringObjects: []cloudGenericStringObject{cloudEntry("u1", mcpItem("same", "x"))},
+		Folders:              []cloudFolder{{Name: "已有", Metadata: objectMetadata{UID: "c2"}}},
+	}
+	plan := planRestore(bd, buildCloudInventory(cloud), restoreOptions{Strategy: conflictSkip})
+	want := map[string]planAction{
+		"folder:新文件夹": actionCreate,
+		"folder:已有":   actionSkipExisting,
+		"object:new":  actionCreate,
+		"object:same": actionSkipExisting,
+		"object:":     actionInvalid,
+		"workflow:":   actionInvalid,
+	}
+	got := map[string]planAction{}
+	for _, it := range plan.Items {
+		got[it.Kind+":"+it.Name] = it.Action
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("动作 %v，期望 %v", got, want)
+	}
+	if plan.ExistingFolder["f2"] != "c2" {
+		t.Errorf("ExistingFolder = %v，期望 f2 -> c2", plan.ExistingFolder)
+	}
+	if c := plan.Counts(); c != (planCounts{Create: 2, Skip: 2, Invalid: 2}) {
+		t.Errorf("Counts = %+v", c)
+	}
+}
+
+// 执行结果的统计与预览时的计划一致
+func TestExecuteRestorePlanMatchesPlan(t *testing.T) {
+	f, client := newFakeGraphQL(t)
+	bd := BackupData{
+		Folders:    []map[string]any{{"uid": "f1", "name": "F"}},
+		MCPServers: []map[string]any{mcpItem("new", "x"), mcpItem("same", "x"), mcpItem("changed", "new"), {"format": formatMCPServer}},
+		Rules:      []map[string]any{ruleItem("r", "x")},
+		Workflows:  []map[string]any{{"data": `{"name":"w","command":"ls"}`, "folderId": "f1"}},
+	}
+	inv := inventoryOf(cloudEntry("u1", mcpItem("same", "x")), cloudEntry("u2", mcpItem("changed", "old")))
+	for _, strategy := range conflictStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			plan := planRestore(bd, inv, restoreOptions{Strategy: strategy})
+			c := plan.Counts()
+			res := executeRestorePlan(context.Background(), client, plan, "user", restoreLimits{Concurrency: 3})
+			if !res.Success || res.TotalSuccess != c.Create+c.Update || res.TotalSkipped != c.Skip || res.TotalFailed != 0 {
+				t.Errorf("结果 成功 %d 跳过 %d 失败 %d，计划 %+v", res.TotalSuccess, res.TotalSkipped, res.TotalFailed, c)
+			}
+			if len(res.Conflicts) != c.Conflicts {
+				t.Errorf("冲突 %d，计划 %d", len(res.Conflicts), c.Conflicts)
+			}
+		})
+	}
+	if f.ops["GetUpdatedCloudObjects"] != 0 {
+		t.Errorf("执行计划不应重新获取云端对象: %v", f.ops)
+	}
+}
+
+func TestPlanRestoreConflictStrategies(t *testing.T) {
+	bd := BackupData{MCPServers: []map[string]any{mcpItem("a", "one")}}
+	// 云端已有同名对象，且已有一个 "a (恢复)"，重命名时需继续编号
+	inv := inventoryOf(cloudEntry("u1", mcpItem("a", "two")), cloudEntry("u2", mcpItem("a (恢复)", "x")))
+	tests := []struct {
+		strategy conflictStrategy
+		action   planAction
+		name     string // 计划中的名称
+		original string // 重命名前的名称
+		existing string
+	}{
+		{conflictSkip, actionSkipConflict, "a", "", "u1"},
+		{conflictOverwrite, actionUpdate, "a", "", "u1"},
+		{conflictRename, actionCreate, "a (恢复 2)", "a", "u1"},
+		{conflictKeepBoth, actionCreate, "a", "", "u1"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			plan := planRestore(bd, inv, restoreOptions{Strategy: tt.strategy})
+			it := plan.Items[0]
+			if it.Action != tt.action || it.Name != tt.name || it.OriginalName != tt.original || it.ExistingUID != tt.existing || !it.Conflict {
+				t.Errorf("计划 %s %q（原名 %q，覆盖 %q，冲突 %v），期望 %s %q（原名 %q，覆盖 %q）",
+					it.Action, it.Name, it.OriginalName, it.ExistingUID, it.Conflict, tt.action, tt.name, tt.original, tt.existing)
+			}
+			if objectName(it.Format, it.Data) != tt.name {
+				t.Errorf("serializedModel 中的名称为 %q，期望 %q", objectName(it.Format, it.Data), tt.name)
+			}
+		})
+	}
+}
+
+// 同一计划中多个冲突条目重命名时依次编号，不与云端、备份中的其他条目或彼此重名
+func TestPlanRestoreRenameCollisions(t *testing.T) {
+	bd := BackupData{MCPServers: []map[string]any{mcpItem("a", "one"), mcpItem("a", "three"), mcpItem("a (恢复 2)", "four")}}
+	inv := inventoryOf(cloudEntry("u1", mcpItem("a", "two")), cloudEntry("u2", mcpItem("a (恢复)", "x")))
+	plan := planRestore(bd, inv, restoreOptions{Strategy: conflictRename})
+	var names []string
+	for _, it := range plan.Items {
+		names = append(names, it.Name)
+	}
+	// 第三个条目本身名为 "a (恢复 2)"，保留原名，副本跳过这个编号
+	want := []string{"a (恢复 3)", "a (恢复 4)", "a (恢复 2)"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("名称 %v，期望 %v", names, want)
+	}
+}
+
+func TestPlanRestoreOverwriteWithoutUID(t *testing.T) {
+	bd := BackupData{MCPServers: []map[string]any{mcpItem("a", "one")}}
+	plan := planRestore(bd, inventoryOf(cloudEntry("", mcpItem("a", "two"))), restoreOptions{Strategy: conflictOverwrite})
+	if it := plan.Items[0]; it.Action != actionInvalid || !it.Conflict {
+		t.Errorf("云端对象缺少 uid 时应无法覆盖，实际 %s", it.Action)
+	}
+}
+
+func TestExecuteRestorePlanConflicts(t *testing.T) {
+	f, client := newFakeGraphQL(t)
+	bd := BackupData{MCPServers: []map[string]any{mcpItem("a", "one")}}
+	inv := inventoryOf(cloudEntry("u1", mcpItem("a", "two")))
+	tests := []struct {
+		strategy conflictStrategy
+		op       string // 期望发出的 mutation，空表示不发送
+		outcome  string
+	}{
+		{conflictSkip, "", "已跳过"},
+		{conflictOverwrite, "UpdateGenericStringObject", "已覆盖"},
+		{conflictRename, "CreateGenericStringObject", "已创建副本 a (恢复)"},
+		{conflictKeepBoth, "CreateGenericStringObject", "已创建"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			before := map[string]int{}
+			for k, v := range f.ops {
+				before[k] = v
+			}
+			res := executeRestorePlan(context.Background(), client, planRestore(bd, inv, restoreOptions{Strategy: tt.strategy}), "user", restoreLimits{Concurrency: 1})
+			if len(res.Conflicts) != 1 || res.Conflicts[0].Name != "a" || res.Conflicts[0].Outcome != tt.outcome {
+				t.Fatalf("冲突 %+v，期望结果 %q", res.Conflicts, tt.outcome)
+			}
+			for op, n := range f.ops {
+				want := before[op]
+				if op == tt.op {
+					want++
+				}
+				if n != want {
+					t.Errorf("%s 调用 %d 次，期望 %d", op, n-before[op], want-before[op])
+				}
+			}
+		})
+	}
+}
+
+func TestPlanRestoreSelection(t *testing.T) {
+	bd := BackupData{
+		BackupType: "global",
+		Folders:    []map[string]any{{"uid": "f1", "name": "F"}},
+		MCPServers: []map[string]any{
+			{"format": formatMCPServer, "serializedModel": `{"name":"a","command":"x"}`, "folderId": "f1"},
+			mcpItem("b", "x"),
+		},
+		Rules: []map[string]any{ruleItem("r", "x")},
+	}
+	items := backupItems(bd)
+	ids := map[string]string{} // 名称 -> 条目 ID
+	for _, it := range items {
+		ids[it.Name] = it.ID
+	}
+	cloud := updatedCloudObjects{
+		GenericStringObjects: []cloudGenericStringObject{cloudEntry("u1", mcpItem("b", "old"))},
+		Folders:              []cloudFolder{{Name: "F", Metadata: objectMetadata{UID: "c1"}}},
+	}
+	tests := []struct {
+		name     string
+		selected map[string]bool
+		want     []string // 计划中的条目名称
+	}{
+		{"全部", nil, []string{"F", "a", "b", "r"}},
+		{"只选对象", map[string]bool{ids["a"]: true, ids["r"]: true}, []string{"a", "r"}},
+		{"空选择", map[string]bool{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planRestore(bd, buildCloudInventory(cloud), restoreOptions{Selected: tt.selected, Mirror: true})
+			var got []string
+			for _, it := range plan.Items {
+				got = append(got, it.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("条目 %v，期望 %v", got, tt.want)
+			}
+			// 未选中的文件夹在云端已有同路径文件夹时，选中的对象仍放回其中
+			if plan.ExistingFolder["f1"] != "c1" {
+				t.Errorf("ExistingFolder = %v，期望 f1 -> c1", plan.ExistingFolder)
+			}
+			// 镜像与整份备份比较：未选中的 b 对应的云端对象不会被移除
+			if len(plan.Removals) != 0 {
+				t.Errorf("移除 %v，期望不移除", removalUIDs(plan))
+			}
+		})
+	}
+}
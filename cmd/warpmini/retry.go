@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 重试策略：GraphQL 请求和令牌刷新共用。网络错误、429 和 5xx 按指数退避加随机抖动重试，
+// 服务端给出 Retry-After 时按其等待。创建类 mutation 不是幂等的，只在确定请求未被服务端处理时
+// （连接未建立、429/503 拒绝）才重试，避免超时后重发导致云端出现重复对象；
+// 带 uniquenessKey 的创建除外（见 keyedCreateOps）。
+
+// retryPolicy 重试参数
+type retryPolicy struct {
+	MaxAttempts int           // 含第一次在内的最多尝试次数
+	BaseDelay   time.Duration // 第一次重试前的基准等待
+	MaxDelay    time.Duration // 单次等待上限（Retry-After 也不超过该值）
+}
+
+var defaultRetryPolicy = retryPolicy{MaxAttempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+
+// retryWait 等待 d，ctx 结束时提前返回其错误；测试中替换为只记录不等待
+var retryWait = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do 调用 send 直到成功、遇到不可重试的结果或达到最大次数；返回最后一次的响应或错误。
+// send 每次都必须构造新的请求（请求体不能复用），并使用同一个 ctx；ctx 结束后不再重试。
+func (p retryPolicy) do(ctx context.Context, idempotent bool, send func() (*http.Response, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+		retry, after := shouldRetry(resp, err, idempotent)
+		if !retry || attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := retryWait(ctx, p.backoff(attempt, after)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间：优先 Retry-After，否则指数退避加全抖动
+func (p retryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// shouldRetry 判断结果是否可重试，并返回服务端要求的等待时间
+func shouldRetry(resp *http.Response, err error, idempotent bool) (bool, time.Duration) {
+	if errors.Is(err, errNetworkConfig) {
+		return false, 0
+	}
+	if err != nil {
+		return idempotent || requestNotSent(err), 0
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// 服务端明确拒绝，未处理请求
+		return true, parseRetryAfter(resp.Header.Get("Retry-After"))
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent, parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return false, 0
+}
+
+// requestNotSent 错误是否发生在请求发出之前（DNS 解析失败、连接被拒绝等）
+func requestNotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期），无法解析时为 0
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// clientTimeoutError 返回 http.Client.Timeout 触发时的真实错误
+func clientTimeoutError(t *testing.T) error {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	_, err := (&http.Client{Timeout: 20 * time.Millisecond}).Get(srv.URL)
+	if err == nil {
+		t.Fatal("请求应当超时")
+	}
+	return err
+}
+
+func TestShouldRetry(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	timeoutErr := clientTimeoutError(t)
+	status := func(code int, retryAfter string) *http.Response {
+		h := http.Header{}
+		if retryAfter != "" {
+			h.Set("Retry-After", retryAfter)
+		}
+		return &http.Response{StatusCode: code, Header: h}
+	}
+
+	tests := []struct {
+		name       string
+		resp       *http.Response
+		err        error
+		idempotent bool
+		retry      bool
+		after      time.Duration
+	}{
+		{"客户端超时（幂等）", nil, timeoutErr, true, true, 0},
+		{"客户端超时（创建）", nil, timeoutErr, false, false, 0},
+		{"连接被拒绝（创建）", nil, dialErr, false, true, 0},
+		{"DNS 失败（创建）", nil, &net.DNSError{Err: "no such host", Name: "app.warp.dev"}, false, true, 0},
+		{"读取中断（幂等）", nil, readErr, true, true, 0},
+		{"读取中断（创建）", nil, readErr, false, false, 0},
+		{"网络设置有误", nil, fmt.Errorf("%w: 代理地址无效", errNetworkConfig), true, false, 0},
+		{"429 带 Retry-After", status(http.StatusTooManyRequests, "3"), nil, false, true, 3 * time.Second},
+		{"503", status(http.StatusServiceUnavailable, ""), nil, false, true, 0},
+		{"500（幂等）", status(http.StatusInternalServerError, ""), nil, true, true, 0},
+		{"502（创建）", status(http.StatusBadGateway, ""), nil, false, false, 0},
+		{"400", status(http.StatusBadRequest, ""), nil, true, false, 0},
+		{"200", status(http.StatusOK, ""), nil, true, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, after := shouldRetry(tt.resp, tt.err, tt.idempotent)
+			if retry != tt.retry || after != tt.after {
+				t.Errorf("shouldRetry = (%v, %v)，期望 (%v, %v)", retry, after, tt.retry, tt.after)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := defaultRetryPolicy.do(ctx, true, func() (*http.Response, error) {
+		calls++
+		cancel()
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("取消后应立即返回：err=%v，调用 %d 次", err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := retryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, full := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 70: time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt, 0); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %v，应在 [%v, %v] 内", attempt, d, full/2, full)
+			}
+		}
+	}
+	if d := p.backoff(1, 300*time.Millisecond); d != 300*time.Millisecond {
+		t.Errorf("有 Retry-After 时应按其等待，实际 %v", d)
+	}
+	if d := p.backoff(1, time.Hour); d != time.Second {
+		t.Errorf("Retry-After 不应超过 MaxDelay，实际 %v", d)
+	}
+}
+
+// stubRetryWait 让 retryWait 只记录等待时间
+func stubRetryWait(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	orig := retryWait
+	retryWait = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { retryWait = orig })
+	return &waits
+}
+
+func TestRetryDo(t *testing.T) {
+	p := retryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		name       string
+		statuses   []int
+		idempotent bool
+		calls      int
+		final      int
+	}{
+		{"503 后成功", []int{503, 503, 200}, false, 3, 200},
+		{"达到最大次数", []int{500, 500, 500, 500}, true, 3, 500},
+		{"创建遇到 500 不重试", []int{500, 200}, false, 1, 500},
+		{"400 不重试", []int{400, 200}, true, 1, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := stubRetryWait(t)
+			calls := 0
+			resp, err := p.do(context.Background(), tt.idempotent, func() (*http.Response, error) {
+				rec := httptest.NewRecorder()
+				rec.WriteHeader(tt.statuses[calls])
+				calls++
+				return rec.Result(), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if calls != tt.calls || resp.StatusCode != tt.final || len(*waits) != tt.calls-1 {
+				t.Errorf("调用 %d 次、最终 %d、等待 %d 次，期望 %d 次、%d", calls, resp.StatusCode, len(*waits), tt.calls, tt.final)
+			}
+		})
+	}
+}
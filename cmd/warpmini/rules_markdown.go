@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 规则与 Markdown 互转：每条 JsonAIFact 规则导出为一个 .md 文件，便于在 git 中审阅。
+// 文件以 front-matter 开头，memory 中除 content 外的字段按 "键: JSON 值" 逐行写出
+// （JSON 标量同时也是合法的 YAML），memory 以外的顶层字段加 "fact." 前缀；正文即规则内容。
+//
+//	---
+//	name: "代码风格"
+//	is_autogenerated: false
+//	---
+//	规则内容…
+
+const (
+	frontMatterDelim    = "---"
+	ruleContentKey      = "content"
+	ruleTopLevelPrefix  = "fact."
+	ruleMarkdownFileExt = ".md"
+)
+
+// errNoFrontMatter 文件不以 front-matter 开头，不是规则文件（如 README）
+var errNoFrontMatter = errors.New("缺少 front-matter")
+
+// ruleToMarkdown 把 JsonAIFact 的 serializedModel 转为 Markdown，返回规则名称
+func ruleToMarkdown(serialized string) (string, []byte, error) {
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return "", nil, err
+	}
+	memory, ok := model["memory"].(map[string]any)
+	if !ok {
+		return "", nil, errors.New("规则缺少 memory")
+	}
+	name := asString(memory["name"])
+	meta := map[string]any{}
+	for k, v := range memory {
+		if k != ruleContentKey {
+			meta[k] = v
+		}
+	}
+	for k, v := range model {
+		if k != "memory" {
+			meta[ruleTopLevelPrefix+k] = v
+		}
+	}
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	// name 在最前，其余按键名排序，保证重复导出结果一致
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == "name") != (keys[j] == "name") {
+			return keys[i] == "name"
+		}
+		return keys[i] < keys[j]
+	})
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelim + "\n")
+	for _, k := range keys {
+		v, err := json.Marshal(meta[k])
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", k, v)
+	}
+	buf.WriteString(frontMatterDelim + "\n")
+	// 正文后固定补一个换行，导入时去掉，内容原样往返
+	buf.WriteString(asString(memory[ruleContentKey]))
+	buf.WriteString("\n")
+	return name, buf.Bytes(), nil
+}
+
+// ruleFromMarkdown 解析 ruleToMarkdown 生成的 Markdown，返回 JsonAIFact 的 serializedModel
+func ruleFromMarkdown(data []byte) (string, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelim+"\n") {
+		return "", errNoFrontMatter
+	}
+	head, body, ok := strings.Cut(text[len(frontMatterDelim)+1:], "\n"+frontMatterDelim+"\n")
+	if !ok {
+		// front-matter 之后没有正文
+		head, ok = strings.CutSuffix(strings.TrimRight(text[len(frontMatterDelim)+1:], "\n"), "\n"+frontMatterDelim)
+		if !ok {
+			return "", errors.New("front-matter 未结束")
+		}
+	}
+	model := map[string]any{}
+	memory := map[string]any{}
+	for i, line := range strings.Split(head, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		k, raw, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", fmt.Errorf("front-matter 第 %d 行格式错误: %s", i+1, line)
+		}
+		k, raw = strings.TrimSpace(k), strings.TrimSpace(raw)
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			// 手写的未加引号的字符串
+			v = raw
+		}
+		if top, ok := strings.CutPrefix(k, ruleTopLevelPrefix); ok {
+			model[top] = v
+		} else {
+			memory[k] = v
+		}
+	}
+	if asString(memory["name"]) == "" {
+		return "", errors.New("front-matter 缺少 name")
+	}
+	memory[ruleContentKey] = strings.TrimSuffix(body, "\n")
+	model["memory"] = memory
+	b, err := json.Marshal(model)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ruleFileName 由规则名称生成文件名，保留字母（含中文）、数字、-、_
+func ruleFileName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	s := strings.Trim(sb.String(), "_")
+	if s == "" {
+		s = "rule"
+	}
+	return s
+}
+
+// writeRulesMarkdown 把备份中的规则逐条写入 dir，返回写入的文件数和无法转换的条目说明
+func writeRulesMarkdown(bd BackupData, dir string) (int, []string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, nil, err
+	}
+	used := map[string]bool{}
+	var skipped []string
+	n := 0
+	for i, m := range bd.genericObjects() {
+		if asString(m["format"]) != formatAIFact {
+			continue
+		}
+		name, md, err := ruleToMarkdown(asString(m["serializedModel"]))
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("第 %d 个对象: %v", i+1, err))
+			continue
+		}
+		base := ruleFileName(name)
+		file := base + ruleMarkdownFileExt
+		for j := 2; used[file]; j++ {
+			file = fmt.Sprintf("%s-%d%s", base, j, ruleMarkdownFileExt)
+		}
+		used[file] = true
+		if err := os.WriteFile(filepath.Join(dir, file), md, 0o644); err != nil {
+			return n, skipped, err
+		}
+		n++
+	}
+	return n, skipped, nil
+}
+
+// readRulesMarkdown 读取 dir 下全部 .md 文件并转为 JsonAIFact 对象（按文件名排序）；
+// 没有 front-matter 的文件不是规则，跳过并返回其文件名，front-matter 有误的文件报错
+func readRulesMarkdown(dir string) ([]map[string]any, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+ruleMarkdownFileExt))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(files)
+	rules := []map[string]any{}
+	var skipped []string
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		serialized, err := ruleFromMarkdown(data)
+		if errors.Is(err, errNoFrontMatter) {
+			skipped = append(skipped, filepath.Base(path))
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		rules = append(rules, map[string]any{
+			"format":          formatAIFact,
+			"serializedModel": serialized,
+		})
+	}
+	if len(rules) == 0 {
+		return nil, skipped, fmt.Errorf("%s 中没有带 front-matter 的 %s 规则文件", dir, ruleMarkdownFileExt)
+	}
+	return rules, skipped, nil
+}
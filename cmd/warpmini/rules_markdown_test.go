@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRuleMarkdownRoundTrip(t *testing.T) {
+	models := []string{
+		`{"memory":{"name":"代码风格","content":"- 使用 gofmt\n- 注释用中文\n","is_autogenerated":false},"suggested_by":"user"}`,
+		`{"memory":{"name":"空规则","content":""}}`,
+		`{"memory":{"name":"含分隔线","content":"上\n---\n下"}}`,
+	}
+	for _, model := range models {
+		name, md, err := ruleToMarkdown(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := ruleFromMarkdown(md)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, md)
+		}
+		var want, got any
+		json.Unmarshal([]byte(model), &want)
+		json.Unmarshal([]byte(back), &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s 往返不一致:\n%s\n%s", name, back, model)
+		}
+	}
+}
+
+func TestReadRulesMarkdownSkipsNonRules(t *testing.T) {
+	dir := t.TempDir()
+	_, md, err := ruleToMarkdown(`{"memory":{"name":"r","content":"x"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"r.md":      string(md),
+		"README.md": "# 规则目录\n说明文字\n",
+		"notes.txt": "---\nname: ignored\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, skipped, err := readRulesMarkdown(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || !reflect.DeepEqual(skipped, []string{"README.md"}) {
+		t.Errorf("导入 %d 条，跳过 %v；期望 1 条，跳过 [README.md]", len(rules), skipped)
+	}
+
+	// front-matter 有误的规则文件仍然报错
+	os.WriteFile(filepath.Join(dir, "bad.md"), []byte("---\nno name here\n---\n"), 0o644)
+	if _, _, err := readRulesMarkdown(dir); err == nil {
+		t.Error("front-matter 有误时应报错")
+	}
+}
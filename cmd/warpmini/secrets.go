@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 密钥脱敏：备份时把 MCP 配置中像密钥的值（env、headers、带密钥的参数）替换为具名占位符，
+// 便于在团队内共享备份；恢复时依次从环境变量、本地密钥文件、交互输入中填回，
+// 仍有未解析的占位符的条目拒绝上传。
+// 占位符名称来自备份文件，可能由他人生成：只有 WARPMINI_SECRET_ 开头的名称才从环境变量读取，
+// 避免共享的备份借占位符读出 AWS_SECRET_ACCESS_KEY 等无关的环境变量。
+
+const (
+	secretsFileName  = "secrets.json"
+	secretNamePrefix = "WARPMINI_SECRET_"
+)
+
+var (
+	// 键名像密钥
+	secretKeyPattern = regexp.MustCompile(`(?i)(api[_-]?key|token|secret|passw(or)?d|auth|credential|bearer|cookie|session|private[_-]?key|access[_-]?key)`)
+	// 值本身就像常见的令牌
+	secretValuePattern = regexp.MustCompile(`^(sk-[A-Za-z0-9_-]{16,}|gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|xox[abpr]-[A-Za-z0-9-]{10,}|glpat-[A-Za-z0-9_-]{16,}|AKIA[0-9A-Z]{16}|Bearer\s+\S{8,})$`)
+	// 占位符，如 {{warpmini-secret:WARPMINI_SECRET_GITHUB_TOKEN}}
+	secretPlaceholderPattern = regexp.MustCompile(`\{\{warpmini-secret:([A-Z0-9_]+)\}\}`)
+)
+
+func secretPlaceholder(name string) string {
+	return "{{warpmini-secret:" + name + "}}"
+}
+
+// secretName 生成占位符名称：WARPMINI_SECRET_<服务名>_<键名>，只含大写字母、数字和下划线
+func secretName(server, key string) string {
+	clean := func(s string) string {
+		s = strings.ToUpper(strings.TrimLeft(s, "-"))
+		var sb strings.Builder
+		for _, r := range s {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune('_')
+			}
+		}
+		return strings.Trim(sb.String(), "_")
+	}
+	parts := []string{strings.TrimSuffix(secretNamePrefix, "_")}
+	if s := clean(server); s != "" {
+		parts = append(parts, s)
+	}
+	if k := clean(key); k != "" {
+		parts = append(parts, k)
+	}
+	return strings.Join(parts, "_")
+}
+
+// redactMCPSecrets 脱敏单个 JsonMCPServer 的 serializedModel，返回新内容和生成的占位符名称
+func redactMCPSecrets(serialized string) (string, []string, error) {
+	var model map[string]any
+	if err := json.Unmarshal([]byte(serialized), &model); err != nil {
+		return "", nil, err
+	}
+	server, _ := model["name"].(string)
+	used := map[string]bool{}
+	var names []string
+	newName := func(key string) string {
+		base := secretName(server, key)
+		name := base
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[name] = true
+		names = append(names, name)
+		return name
+	}
+	redacted := redactValue(model, "", newName).(map[string]any)
+	b, err := json.Marshal(redacted)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(b), names, nil
+}
+
+// redactValue 递归处理 JSON 值；key 为该值所在的键名。
+// 按键名排序遍历，同名占位符的 _2、_3 后缀在每次备份中都落在相同的字段上
+func redactValue(v any, key string, newName func(string) string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			t[k] = redactValue(t[k], k, newName)
+		}
+		return t
+	case []any:
+		return redactArgs(t, key, newName)
+	case string:
+		if t == "" || secretPlaceholderPattern.MatchString(t) {
+			return t
+		}
+		if (key != "" && secretKeyPattern.MatchString(key)) || secretValuePattern.MatchString(t) {
+			return secretPlaceholder(newName(key))
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// redactArgs 处理参数列表：--api-key=xxx、--token xxx 以及形如令牌的裸值
+func redactArgs(args []any, key string, newName func(string) string) []any {
+	for i := 0; i < len(args); i++ {
+		s, ok := args[i].(string)
+		if !ok {
+			args[i] = redactValue(args[i], key, newName)
+			continue
+		}
+		if secretPlaceholderPattern.MatchString(s) {
+			continue
+		}
+		if strings.HasPrefix(s, "-") {
+			flag, val, hasVal := strings.Cut(s, "=")
+			if !secretKeyPattern.MatchString(flag) {
+				continue
+			}
+			if hasVal {
+				if val != "" {
+					args[i] = flag + "=" + secretPlaceholder(newName(flag))
+				}
+			} else if i+1 < len(args) {
+				if next, ok := args[i+1].(string); ok && next != "" && !strings.HasPrefix(next, "-") {
+					args[i+1] = secretPlaceholder(newName(flag))
+					i++
+				}
+			}
+			continue
+		}
+		if secretValuePattern.MatchString(s) {
+			args[i] = secretPlaceholder(newName(fmt.Sprintf("%s_%d", key, i)))
+		}
+	}
+	return args
+}
+
+// redactBackupSecrets 脱敏备份中的全部 MCP 配置，返回所有占位符名称（已排序）
+func redactBackupSecrets(bd *BackupData) ([]string, error) {
+	var all []string
+	for _, m := range bd.MCPServers {
+		serialized := asString(m["serializedModel"])
+		if serialized == "" {
+			continue
+		}
+		redacted, names, err := redactMCPSecrets(serialized)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏 MCP 配置失败: %w", err)
+		}
+		m["serializedModel"] = redacted
+		all = append(all, names...)
+	}
+	sort.Strings(all)
+	return all, nil
+}
+
+// placeholderNames 返回内容中出现的占位符名称
+func placeholderNames(data string) []string {
+	var names []string
+	for _, m := range secretPlaceholderPattern.FindAllStringSubmatch(data, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// fillPlaceholders 用 values 填回占位符，返回填充后的内容和未能解析的名称
+func fillPlaceholders(data string, values map[string]string) (string, []string) {
+	var missing []string
+	seen := map[string]bool{}
+	filled := secretPlaceholderPattern.ReplaceAllStringFunc(data, func(ph string) string {
+		name := secretPlaceholderPattern.FindStringSubmatch(ph)[1]
+		if v, ok := values[name]; ok && v != "" {
+			// 值写在 JSON 字符串内部，需要转义
+			b, _ := json.Marshal(v)
+			return string(b[1 : len(b)-1])
+		}
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return ph
+	})
+	return filled, missing
+}
+
+// secretsFilePath 返回本地密钥文件路径 ~/.warp_config/secrets.json
+func secretsFilePath() (string, error) {
+	dir, err := warpConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secretsFileName), nil
+}
+
+// lookupSecrets 依次从环境变量（仅 WARPMINI_SECRET_ 开头的名称）和本地密钥文件中查找占位符的值，
+// prompted 为交互输入的值（优先级最低，只补缺）
+func lookupSecrets(names []string, prompted map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	var fileValues map[string]string
+	if path, err := secretsFilePath(); err == nil {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &fileValues); err != nil {
+				return nil, fmt.Errorf("密钥文件 %s 格式错误: %w", path, err)
+			}
+		}
+	}
+	for _, name := range names {
+		if v := secretFromEnv(name); v != "" {
+			values[name] = v
+		} else if v := fileValues[name]; v != "" {
+			values[name] = v
+		} else if v := prompted[name]; v != "" {
+			values[name] = v
+		}
+	}
+	return values, nil
+}
+
+// secretFromEnv 从环境变量读取占位符的值，名称不以 WARPMINI_SECRET_ 开头时不读取
+func secretFromEnv(name string) string {
+	if !strings.HasPrefix(name, secretNamePrefix) {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// unresolvedSecretsNote 列出因密钥占位符未解析而未上传的条目，附加到恢复结果的状态文字后
+func unresolvedSecretsNote(res RestoreResult) string {
+	if len(res.UnresolvedSecrets) == 0 {
+		return ""
+	}
+	return "；密钥未解析未上传：" + strings.Join(res.UnresolvedSecrets, "；")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactMCPSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		want  map[string]any // 脱敏后各字段的值
+		names []string
+	}{
+		{
+			name:  "env 和 headers",
+			model: `{"name":"github","env":{"GITHUB_TOKEN":"ghp_abc","LOG_LEVEL":"debug"},"headers":{"Authorization":"Bearer xyz"}}`,
+			want: map[string]any{
+				"env":     map[string]any{"GITHUB_TOKEN": "{{warpmini-secret:WARPMINI_SECRET_GITHUB_GITHUB_TOKEN}}", "LOG_LEVEL": "debug"},
+				"headers": map[string]any{"Authorization": "{{warpmini-secret:WARPMINI_SECRET_GITHUB_AUTHORIZATION}}"},
+			},
+			names: []string{"WARPMINI_SECRET_GITHUB_GITHUB_TOKEN", "WARPMINI_SECRET_GITHUB_AUTHORIZATION"},
+		},
+		{
+			name:  "参数",
+			model: `{"name":"svc","args":["--api-key=k1","--token","t1","--verbose","sk-abcdefghijklmnopqrstu"]}`,
+			want: map[string]any{
+				"args": []any{
+					"--api-key={{warpmini-secret:WARPMINI_SECRET_SVC_API_KEY}}",
+					"--token",
+					"{{warpmini-secret:WARPMINI_SECRET_SVC_TOKEN}}",
+					"--verbose",
+					"{{warpmini-secret:WARPMINI_SECRET_SVC_ARGS_4}}",
+				},
+			},
+			names: []string{"WARPMINI_SECRET_SVC_API_KEY", "WARPMINI_SECRET_SVC_TOKEN", "WARPMINI_SECRET_SVC_ARGS_4"},
+		},
+		{
+			name:  "已是占位符",
+			model: `{"name":"svc","env":{"TOKEN":"{{warpmini-secret:WARPMINI_SECRET_SVC_TOKEN}}"}}`,
+			want:  map[string]any{"env": map[string]any{"TOKEN": "{{warpmini-secret:WARPMINI_SECRET_SVC_TOKEN}}"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, names, err := redactMCPSecrets(tt.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if !reflect.DeepEqual(got[k], v) {
+					t.Errorf("%s = %v，期望 %v", k, got[k], v)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("占位符 %v，期望 %v", names, tt.names)
+			}
+		})
+	}
+}
+
+// 同名占位符的后缀不能随 map 遍历顺序变化，否则恢复时值会填错字段、备份哈希每次都不同
+func TestRedactMCPSecretsDeterministic(t *testing.T) {
+	const model = `{"name":"x","args":["--token","t-args"],"env":{"TOKEN":"t-env"},"headers":{"token":"t-header"}}`
+	first, names, err := redactMCPSecrets(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"WARPMINI_SECRET_X_TOKEN", "WARPMINI_SECRET_X_TOKEN_2", "WARPMINI_SECRET_X_TOKEN_3"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("占位符 %v，期望 %v", names, want)
+	}
+	if !strings.Contains(first, `"TOKEN":"{{warpmini-secret:WARPMINI_SECRET_X_TOKEN_2}}"`) {
+		t.Errorf("env.TOKEN 应为 WARPMINI_SECRET_X_TOKEN_2: %s", first)
+	}
+	for i := 0; i < 50; i++ {
+		out, _, err := redactMCPSecrets(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != first {
+			t.Fatalf("第 %d 次结果不同:\n%s\n%s", i+2, out, first)
+		}
+	}
+}
+
+// 占位符名称来自备份文件，只有 WARPMINI_SECRET_ 开头的才从环境变量读取
+func TestLookupSecretsEnvPrefix(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "aws-env")
+	t.Setenv("WARPMINI_SECRET_A", "a-env")
+	path, err := secretsFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"AWS_SECRET_ACCESS_KEY":"aws-file","WARPMINI_SECRET_A":"a-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lookupSecrets([]string{"AWS_SECRET_ACCESS_KEY", "WARPMINI_SECRET_A", "WARPMINI_SECRET_B"}, map[string]string{"WARPMINI_SECRET_B": "b-prompt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"AWS_SECRET_ACCESS_KEY": "aws-file", "WARPMINI_SECRET_A": "a-env", "WARPMINI_SECRET_B": "b-prompt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lookupSecrets = %v，期望 %v", got, want)
+	}
+}
+
+// 未解析的占位符按条目出现在恢复结果中
+func TestRestoreReportsUnresolvedSecrets(t *testing.T) {
+	bd := BackupData{MCPServers: []map[string]any{
+		{"format": formatMCPServer, "serializedModel": `{"name":"gh","env":{"GITHUB_TOKEN":"{{warpmini-secret:WARPMINI_SECRET_GH_GITHUB_TOKEN}}"}}`},
+	}}
+	plan := planRestore(bd, cloudInventory{}, restoreOptions{})
+	if len(plan.Items) != 1 || plan.Items[0].Action != actionInvalid {
+		t.Fatalf("计划 %+v，期望一个无效条目", plan.Items)
+	}
+	if want := []string{"WARPMINI_SECRET_GH_GITHUB_TOKEN"}; !reflect.DeepEqual(plan.Items[0].MissingSecrets, want) {
+		t.Errorf("MissingSecrets = %v，期望 %v", plan.Items[0].MissingSecrets, want)
+	}
+	res := executeRestorePlan(context.Background(), nil, plan, "", restoreLimits{Concurrency: 1})
+	if len(res.UnresolvedSecrets) != 1 || !strings.Contains(res.UnresolvedSecrets[0], "WARPMINI_SECRET_GH_GITHUB_TOKEN") {
+		t.Errorf("UnresolvedSecrets = %v", res.UnresolvedSecrets)
+	}
+	if !strings.Contains(unresolvedSecretsNote(res), "gh") {
+		t.Errorf("提示中应包含条目名称: %q", unresolvedSecretsNote(res))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 本地设置：~/.warp_config/settings.json。环境变量优先于设置文件，便于在脚本或 CI 中临时覆盖。
+
+const (
+	settingsFileName = "settings.json"
+	envBackupDir     = "WARPMINI_BACKUP_DIR"
+)
+
+// appSettings 设置文件结构，字段为空表示使用默认值
+type appSettings struct {
+	BackupDir string `json:"backup_dir,omitempty"` // 备份根目录，默认 ~/.warp_config/backups
+
+	AutoBackupOnLogin bool `json:"auto_backup_on_login,omitempty"` // 登录后自动备份一次
+	AutoBackupMinutes int  `json:"auto_backup_minutes,omitempty"`  // 自动备份间隔（分钟），0 表示不定时
+
+	RestoreConcurrency   int `json:"restore_concurrency,omitempty"`     // 恢复时同时进行的请求数
+	RestoreRatePerSecond int `json:"restore_rate_per_second,omitempty"` // 恢复时每秒最多发出的请求数
+
+	// 服务端地址，见 endpoints.go
+	GraphQLURL       string `json:"graphql_url,omitempty"`
+	FirebaseTokenURL string `json:"firebase_token_url,omitempty"`
+	FirebaseAPIKey   string `json:"firebase_api_key,omitempty"`
+
+	// 代理与 TLS，见 network.go
+	ProxyURL      string `json:"proxy_url,omitempty"`
+	CAFile        string `json:"ca_file,omitempty"`
+	TLSMinVersion string `json:"tls_min_version,omitempty"`
+}
+
+// 恢复并发的默认值：足够快，又不至于触发服务端限流
+const (
+	defaultRestoreConcurrency   = 4
+	defaultRestoreRatePerSecond = 8
+)
+
+// restoreLimits 返回恢复时的并发数和速率，未设置时使用默认值
+func (s appSettings) restoreLimits() restoreLimits {
+	l := restoreLimits{Concurrency: s.RestoreConcurrency, RatePerSecond: s.RestoreRatePerSecond}
+	if l.Concurrency <= 0 {
+		l.Concurrency = defaultRestoreConcurrency
+	}
+	if l.RatePerSecond <= 0 {
+		l.RatePerSecond = defaultRestoreRatePerSecond
+	}
+	return l
+}
+
+// settingsFilePath 返回设置文件路径
+func settingsFilePath() (string, error) {
+	dir, err := warpConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, settingsFileName), nil
+}
+
+// loadSettings 读取设置文件，不存在时返回默认设置
+func loadSettings() (appSettings, error) {
+	var s appSettings
+	path, err := settingsFilePath()
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("设置文件 %s 格式错误: %w", path, err)
+	}
+	return s, nil
+}
+
+// saveSettings 写入设置文件
+func saveSettings(s appSettings) error {
+	path, err := settingsFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// configuredBackupDir 返回用户指定的备份根目录（环境变量优先），未指定时为空
+func configuredBackupDir() (string, error) {
+	dir := strings.TrimSpace(os.Getenv(envBackupDir))
+	if dir == "" {
+		s, err := loadSettings()
+		if err != nil {
+			return "", err
+		}
+		dir = strings.TrimSpace(s.BackupDir)
+	}
+	if dir == "" {
+		return "", nil
+	}
+	return expandHome(dir)
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return filepath.Clean(path), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// setBackupDir 保存备份根目录设置，dir 为空时恢复默认位置
+func setBackupDir(dir string) error {
+	s, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	s.BackupDir = dir
+	return saveSettings(s)
+}
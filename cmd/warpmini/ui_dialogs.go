@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	fyne "fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showRestorePlanDialog 展示恢复计划（此时尚未做任何修改），确认后调用 onConfirm
+func showRestorePlanDialog(w fyne.Window, plan restorePlan, onConfirm, onCancel func()) {
+	details := widget.NewLabel(strings.Join(plan.Lines(), "\n"))
+	scroll := container.NewVScroll(details)
+	scroll.SetMinSize(fyne.NewSize(520, 260))
+	content := container.NewBorder(widget.NewLabel(plan.Summary()), nil, nil, nil, scroll)
+	d := dialog.NewCustomConfirm("恢复计划预览", "执行恢复", "取消", content, func(ok bool) {
+		if ok {
+			onConfirm()
+		} else if onCancel != nil {
+			onCancel()
+		}
+	}, w)
+	d.Show()
+}
+
+// showConflictsDialog 列出恢复中遇到的同名冲突及处理结果
+func showConflictsDialog(w fyne.Window, conflicts []restoreConflict) {
+	lines := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		lines[i] = c.Item + " → " + c.Outcome
+	}
+	scroll := container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n")))
+	scroll.SetMinSize(fyne.NewSize(520, 200))
+	dialog.ShowCustom("同名冲突", "关闭", scroll, w)
+}
+
+// askPassphrase 弹出口令输入框；confirm 为 true 时要求输入两次（设置口令）
+func askPassphrase(w fyne.Window, title string, confirm bool, onOK func(passphrase string)) {
+	pass := widget.NewPasswordEntry()
+	items := []*widget.FormItem{widget.NewFormItem("口令", pass)}
+	again := widget.NewPasswordEntry()
+	if confirm {
+		items = append(items, widget.NewFormItem("确认口令", again))
+	}
+	dialog.ShowForm(title, "确定", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		switch {
+		case pass.Text == "":
+			dialog.ShowError(errors.New("口令不能为空"), w)
+		case confirm && pass.Text != again.Text:
+			dialog.ShowError(errors.New("两次输入的口令不一致"), w)
+		default:
+			onOK(pass.Text)
+		}
+	}, w)
+}
+
+// askSecrets 逐个询问密钥占位符的值；取消时以空值回调，相关条目将被标记为无效
+func askSecrets(w fyne.Window, names []string, onDone func(values map[string]string)) {
+	entries := make([]*widget.Entry, len(names))
+	items := make([]*widget.FormItem, len(names))
+	for i, name := range names {
+		entries[i] = widget.NewPasswordEntry()
+		items[i] = widget.NewFormItem(name, entries[i])
+	}
+	form := container.NewVScroll(widget.NewForm(items...))
+	form.SetMinSize(fyne.NewSize(520, 200))
+	content := container.NewBorder(widget.NewLabel("以下密钥未在环境变量（仅读取 "+secretNamePrefix+" 开头的名称）或 ~/.warp_config/secrets.json 中找到："), nil, nil, nil, form)
+	dialog.ShowCustomConfirm("填写密钥", "确定", "跳过", content, func(ok bool) {
+		values := map[string]string{}
+		if ok {
+			for i, name := range names {
+				if v := entries[i].Text; v != "" {
+					values[name] = v
+				}
+			}
+		}
+		onDone(values)
+	}, w)
+}
+
+// askRestoreSelection 列出备份中的条目供勾选（默认全选），确认后以选中的条目 ID 回调
+func askRestoreSelection(w fyne.Window, items []restoreItem, onDone func(selected map[string]bool), onCancel func()) {
+	checks := make([]*widget.Check, len(items))
+	list := container.NewVBox()
+	for i, it := range items {
+		checks[i] = widget.NewCheck(it.Label(), nil)
+		checks[i].SetChecked(true)
+		list.Add(checks[i])
+	}
+	setAll := func(v bool) {
+		for _, c := range checks {
+			c.SetChecked(v)
+		}
+	}
+	buttons := container.NewHBox(
+		widget.NewButton("全选", func() { setAll(true) }),
+		widget.NewButton("全不选", func() { setAll(false) }),
+	)
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(520, 300))
+	content := container.NewBorder(buttons, nil, nil, nil, scroll)
+	dialog.ShowCustomConfirm("选择要恢复的条目", "生成计划", "取消", content, func(ok bool) {
+		if !ok {
+			if onCancel != nil {
+				onCancel()
+			}
+			return
+		}
+		selected := map[string]bool{}
+		for i, c := range checks {
+			if c.Checked {
+				selected[items[i].ID] = true
+			}
+		}
+		onDone(selected)
+	}, w)
+}
+
+// pickOpenFile 选择要读取的本地文件，回调得到文件路径
+func pickOpenFile(w fyne.Window, onPick func(path string)) {
+	dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if r == nil {
+			return
+		}
+		path := r.URI().Path()
+		r.Close()
+		onPick(path)
+	}, w)
+}
+
+// pickSaveFile 选择保存位置（默认文件名 name），回调得到文件路径
+func pickSaveFile(w fyne.Window, name string, onPick func(path string)) {
+	d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if wc == nil {
+			return
+		}
+		path := wc.URI().Path()
+		wc.Close()
+		onPick(path)
+	}, w)
+	d.SetFileName(name)
+	d.Show()
+}
+
+// pickFolder 选择本地目录，回调得到目录路径
+func pickFolder(w fyne.Window, onPick func(dir string)) {
+	dialog.ShowFolderOpen(func(u fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if u == nil {
+			return
+		}
+		onPick(u.Path())
+	}, w)
+}
+
+// confirmAction 弹出确认框，确认调用 onOK，取消调用 onCancel
+func confirmAction(w fyne.Window, title, message string, onOK, onCancel func()) {
+	label := widget.NewLabel(message)
+	label.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustomConfirm(title, "继续", "取消", label, func(ok bool) {
+		if ok {
+			onOK()
+		} else if onCancel != nil {
+			onCancel()
+		}
+	}, w)
+	d.Resize(fyne.NewSize(460, 200))
+	d.Show()
+}
+
+// confirmRemovals 镜像模式执行前列出每个将被移到回收站的云端对象，确认后调用 onOK
+func confirmRemovals(w fyne.Window, lines []string, onOK, onCancel func()) {
+	scroll := container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n")))
+	scroll.SetMinSize(fyne.NewSize(520, 240))
+	header := widget.NewLabel(fmt.Sprintf("以下 %d 个云端对象不在备份中，将被移到 Warp Drive 回收站：", len(lines)))
+	header.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(header, nil, nil, nil, scroll)
+	d := dialog.NewCustomConfirm("确认移除", "移除并恢复", "取消", content, func(ok bool) {
+		if ok {
+			onOK()
+		} else if onCancel != nil {
+			onCancel()
+		}
+	}, w)
+	d.Show()
+}